@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/b4fun/kubekit"
+	"github.com/b4fun/kubekit/examples"
+	"github.com/b4fun/kubekit/podstream/bundle"
+)
+
+var (
+	flagNamespace     string
+	flagLabelSelector string
+	flagSince         time.Duration
+	flagSinceTime     string
+	flagUntilTime     string
+	flagOutput        string
+	flagKubeConfig    *string
+)
+
+func setupFlags() {
+	flagKubeConfig = examples.BindCLIFlags(flag.CommandLine)
+	flag.StringVar(&flagNamespace, "namespace", "", "Specify the namespace to use.")
+	flag.StringVar(&flagLabelSelector, "selector", "", "Selector (label query) to filter on. Defaults to all pods in the namespace.")
+	flag.DurationVar(&flagSince, "since", 0, "Only gather logs and events newer than a relative duration like 1h.")
+	flag.StringVar(&flagSinceTime, "since-time", "", "Only gather logs and events after a RFC3339 time.")
+	flag.StringVar(&flagUntilTime, "until-time", "", "Only gather logs and events before a RFC3339 time.")
+	flag.StringVar(&flagOutput, "output", "", "Specify the bundle file to write. Defaults to bundle-<namespace>-<time>.tar.gz.")
+
+	flag.Parse()
+
+	if flagNamespace == "" {
+		flagNamespace = "default"
+	}
+
+	if flagOutput == "" {
+		flagOutput = fmt.Sprintf("bundle-%s-%s.tar.gz", flagNamespace, time.Now().Format("20060102150405"))
+	}
+}
+
+func mustParseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func main() {
+	setupFlags()
+
+	kubeClient, err := examples.GetKubeClient(*flagKubeConfig)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	options := []bundle.Option{
+		bundle.WithLogger(kubekit.LogFunc(func(format string, args ...interface{}) {
+			fmt.Printf(format+"\n", args...)
+		})),
+		bundle.WithLabelSelector(flagLabelSelector),
+	}
+	if flagSince > 0 {
+		options = append(options, bundle.WithSince(flagSince))
+	}
+	if flagSinceTime != "" || flagUntilTime != "" {
+		options = append(options, bundle.WithTimeRange(mustParseTime(flagSinceTime), mustParseTime(flagUntilTime)))
+	}
+
+	f, err := os.Create(flagOutput)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	summary, err := bundle.Gather(ctx, kubeClient, flagNamespace, f, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("gathered %d pods, %d owners, %d events into %s\n", len(summary.Pods), len(summary.Owners), summary.Events, flagOutput)
+	for _, err := range summary.Errors {
+		fmt.Printf("warning: %s\n", err)
+	}
+}
@@ -0,0 +1,183 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// ruleState tracks the matches of a rule.
+type ruleState struct {
+	rule   Rule
+	filter podstream.LogFilter
+
+	// matches lists the matched entries within the window.
+	matches []podstream.LogEntry
+	// lastFired is the time of the last fired alert.
+	lastFired time.Time
+}
+
+// observe counts the entry and returns true if the rule should fire.
+func (s *ruleState) observe(entry podstream.LogEntry) bool {
+	if !s.filter.FilterLog(entry.Log) {
+		return false
+	}
+
+	s.matches = append(s.matches, entry)
+	windowStart := entry.Time.Add(-s.rule.Window)
+	expired := 0
+	for expired < len(s.matches) && s.matches[expired].Time.Before(windowStart) {
+		expired++
+	}
+	s.matches = s.matches[expired:]
+
+	if len(s.matches) < s.rule.Threshold {
+		return false
+	}
+	if !s.lastFired.IsZero() && entry.Time.Before(s.lastFired.Add(s.rule.Cooldown)) {
+		return false
+	}
+	return true
+}
+
+// Alerter is a podstream.LogEntryConsumer that evaluates alert rules against log entries.
+//
+// Matches are counted by log time, so the rules evaluate the same with replayed logs.
+type Alerter struct {
+	logger     logger.Logger
+	httpClient *http.Client
+	handlers   []Handler
+
+	mu    sync.Mutex
+	rules []*ruleState
+}
+
+var (
+	_ podstream.LogEntryConsumer    = (*Alerter)(nil)
+	_ podstream.LogEntryTransformer = (*Alerter)(nil)
+)
+
+func newRuleState(rule Rule) (*ruleState, error) {
+	if rule.Name == "" {
+		return nil, errors.New("rule name is required")
+	}
+
+	state := &ruleState{rule: rule, filter: rule.Filter}
+	if rule.Pattern != "" {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: compile pattern: %w", rule.Name, err)
+		}
+		state.filter = podstream.LogFilterFunc(pattern.MatchString)
+	}
+	if state.filter == nil {
+		return nil, fmt.Errorf("rule %q: pattern or filter is required", rule.Name)
+	}
+
+	if state.rule.Threshold < 1 {
+		state.rule.Threshold = 1
+	}
+	if state.rule.Window <= 0 {
+		state.rule.Window = 1 * time.Minute
+	}
+	if state.rule.Cooldown <= 0 {
+		state.rule.Cooldown = state.rule.Window
+	}
+
+	return state, nil
+}
+
+// New creates an alerter with the given rules.
+func New(rules []Rule, options ...Option) (*Alerter, error) {
+	alerter := &Alerter{
+		logger:     logger.NoOp,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, rule := range rules {
+		state, err := newRuleState(rule)
+		if err != nil {
+			return nil, err
+		}
+		alerter.rules = append(alerter.rules, state)
+	}
+	for _, opt := range options {
+		if err := opt(alerter); err != nil {
+			return nil, err
+		}
+	}
+	if alerter.logger == nil {
+		alerter.logger = logger.NoOp
+	}
+	if len(alerter.handlers) < 1 {
+		return nil, errors.New("alert handler is required")
+	}
+
+	return alerter, nil
+}
+
+// OnLogs evaluates the rules against the logs.
+func (alerter *Alerter) OnLogs(logs []podstream.LogEntry) {
+	alerter.evaluate(logs)
+}
+
+// TransformLogs evaluates the rules against the logs and returns them as is.
+func (alerter *Alerter) TransformLogs(logs []podstream.LogEntry) []podstream.LogEntry {
+	alerter.evaluate(logs)
+	return logs
+}
+
+func (alerter *Alerter) evaluate(logs []podstream.LogEntry) {
+	var alerts []Alert
+
+	alerter.mu.Lock()
+	for _, entry := range logs {
+		for _, state := range alerter.rules {
+			if !state.observe(entry) {
+				continue
+			}
+
+			alerts = append(alerts, Alert{
+				Rule:    state.rule.Name,
+				Count:   len(state.matches),
+				Window:  state.rule.Window,
+				FiredAt: entry.Time,
+				Entries: append([]podstream.LogEntry{}, state.matches...),
+			})
+			state.lastFired = entry.Time
+			state.matches = nil
+		}
+	}
+	alerter.mu.Unlock()
+
+	for _, alert := range alerts {
+		alerter.logger.Log("rule %q fired with %d matches", alert.Rule, alert.Count)
+		for _, handler := range alerter.handlers {
+			handler(alert)
+		}
+	}
+}
+
+// Watch follows the logs of the selected pods and evaluates the alerter rules,
+// until the context is done.
+func Watch(
+	ctx context.Context,
+	podsClient typedcorev1.PodInterface,
+	labelSelector string,
+	alerter *Alerter,
+	options ...podstream.Option,
+) error {
+	options = append(
+		append([]podstream.Option{}, options...),
+		podstream.FollowSelectedPods(labelSelector),
+		podstream.ConsumeLogsWith(alerter),
+	)
+	return podstream.Stream(ctx.Done(), podsClient, options...)
+}
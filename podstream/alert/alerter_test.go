@@ -0,0 +1,109 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	handler := OnAlert(func(Alert) {})
+
+	_, err := New([]Rule{{Name: "no pattern"}}, handler)
+	assert.Error(t, err)
+
+	_, err = New([]Rule{{Name: "invalid", Pattern: "("}}, handler)
+	assert.Error(t, err)
+
+	_, err = New([]Rule{{Pattern: "oom"}}, handler)
+	assert.Error(t, err, "rule name is required")
+
+	_, err = New([]Rule{{Name: "oom", Pattern: "oom"}})
+	assert.Error(t, err, "handler is required")
+}
+
+func TestAlerter(t *testing.T) {
+	var alerts []Alert
+	alerter, err := New(
+		[]Rule{
+			{Name: "oom", Pattern: "(?i)out of memory"},
+			{
+				Name:      "errors",
+				Filter:    podstream.LogFilterFunc(func(log string) bool { return podstream.ParseLevel(log) == podstream.LevelError }),
+				Threshold: 3,
+				Window:    time.Minute,
+			},
+		},
+		OnAlert(func(alert Alert) {
+			alerts = append(alerts, alert)
+		}),
+	)
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	alerter.OnLogs([]podstream.LogEntry{
+		{Time: at(0), Log: "level=error msg=failed"},
+		{Time: at(10), Log: "level=info msg=ok"},
+		{Time: at(70), Log: "level=error msg=failed"},
+		{Time: at(80), Log: "level=error msg=failed"},
+		{Time: at(90), Log: "fatal: Out of memory"},
+		{Time: at(95), Log: "fatal: out of memory"},
+	})
+	assert.Len(t, alerts, 1, "errors should be counted within window, oom should cool down")
+	assert.Equal(t, "oom", alerts[0].Rule)
+
+	logs := alerter.TransformLogs([]podstream.LogEntry{
+		{Time: at(100), Log: "level=error msg=failed"},
+		{Time: at(200), Log: "fatal: out of memory"},
+	})
+	assert.Len(t, logs, 2, "logs should be passed through")
+	if assert.Len(t, alerts, 3) {
+		assert.Equal(t, "errors", alerts[1].Rule)
+		assert.Equal(t, 3, alerts[1].Count)
+		assert.Equal(t, at(100), alerts[1].FiredAt)
+		assert.Len(t, alerts[1].Entries, 3)
+
+		assert.Equal(t, "oom", alerts[2].Rule)
+	}
+}
+
+func TestAlerter_Webhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Alert
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		mu.Lock()
+		received = append(received, alert)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	alerter, err := New(
+		[]Rule{{Name: "oom", Pattern: "out of memory"}},
+		WithWebhook(server.URL),
+	)
+	assert.NoError(t, err)
+
+	alerter.OnLogs([]podstream.LogEntry{{Time: time.Now(), Pod: "pod-0", Log: "out of memory"}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "oom", received[0].Rule)
+		assert.Equal(t, "pod-0", received[0].Entries[0].Pod)
+	}
+}
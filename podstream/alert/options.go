@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the alerter.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(alerter *Alerter) error {
+		alerter.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to post webhooks.
+// Defaults to a client with 10 seconds timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(alerter *Alerter) error {
+		if client == nil {
+			return errors.New("http client is required")
+		}
+
+		alerter.httpClient = client
+		return nil
+	}
+}
+
+// OnAlert adds a handler to be called with fired alerts.
+// Handlers are called in the order they are added.
+func OnAlert(handler Handler) Option {
+	return func(alerter *Alerter) error {
+		if handler == nil {
+			return errors.New("alert handler is required")
+		}
+
+		alerter.handlers = append(alerter.handlers, handler)
+		return nil
+	}
+}
+
+// WithWebhook posts fired alerts to the url as JSON.
+func WithWebhook(url string) Option {
+	return func(alerter *Alerter) error {
+		if url == "" {
+			return errors.New("webhook url is required")
+		}
+
+		alerter.handlers = append(alerter.handlers, func(alert Alert) {
+			if err := alerter.postWebhook(url, alert); err != nil {
+				alerter.logger.Log("failed to post alert %q to webhook: %s", alert.Rule, err)
+			}
+		})
+		return nil
+	}
+}
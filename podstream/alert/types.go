@@ -0,0 +1,43 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Rule fires an alert when matching log entries occur Threshold times within Window.
+type Rule struct {
+	// Name is the rule name.
+	Name string `json:"name"`
+	// Pattern is the regex pattern to match log lines with.
+	Pattern string `json:"pattern,omitempty"`
+	// Filter matches log lines. It is used when Pattern is empty.
+	Filter podstream.LogFilter `json:"-"`
+	// Threshold is the number of matches within Window to fire. Defaults to 1.
+	Threshold int `json:"threshold,omitempty"`
+	// Window is the time window to count matches in. Defaults to 1 minute.
+	Window time.Duration `json:"window,omitempty"`
+	// Cooldown is the min duration between two alerts of the rule. Defaults to Window.
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+}
+
+// Alert is fired when a rule is triggered.
+type Alert struct {
+	// Rule is the name of the triggered rule.
+	Rule string `json:"rule"`
+	// Count is the number of matches within the window.
+	Count int `json:"count"`
+	// Window is the time window of the rule.
+	Window time.Duration `json:"window"`
+	// FiredAt is the time of the log entry triggering the alert.
+	FiredAt time.Time `json:"firedAt"`
+	// Entries lists the matched log entries within the window.
+	Entries []podstream.LogEntry `json:"entries"`
+}
+
+// Handler handles fired alerts.
+type Handler func(alert Alert)
+
+// Option specifies options for configuring the alerter.
+type Option func(alerter *Alerter) error
@@ -0,0 +1,403 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// file is a file to write into the bundle.
+type file struct {
+	name string
+	data []byte
+}
+
+type gatherer struct {
+	logger logger.Logger
+	now    func() time.Time
+
+	client    kubernetes.Interface
+	namespace string
+
+	labelSelector string
+	since         time.Time
+	until         time.Time
+	maxLogBytes   int
+
+	files   []file
+	summary Summary
+	// objects tracks the pods and owners to gather events of
+	objects map[types.UID]struct{}
+}
+
+func (g *gatherer) addFile(name string, data []byte) {
+	g.files = append(g.files, file{name: name, data: data})
+}
+
+func (g *gatherer) addError(format string, args ...interface{}) {
+	err := fmt.Sprintf(format, args...)
+	g.logger.Log(err)
+	g.summary.Errors = append(g.summary.Errors, err)
+}
+
+// addObject adds the object as YAML with its type meta set.
+func (g *gatherer) addObject(name string, obj runtime.Object, gvk schema.GroupVersionKind) {
+	obj = obj.DeepCopyObject()
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		g.addError("encode %s: %s", name, err)
+		return
+	}
+	g.addFile(name, data)
+}
+
+func (g *gatherer) inTimeRange(t time.Time) bool {
+	if !g.since.IsZero() && t.Before(g.since) {
+		return false
+	}
+	if !g.until.IsZero() && !t.Before(g.until) {
+		return false
+	}
+	return true
+}
+
+// Gather captures the logs, manifests, owners, events and hosting node conditions of the
+// pods in the namespace into a tar.gz archive written to w. Items failed to gather are
+// recorded in the summary errors instead of failing the bundle.
+//
+// The archive contains:
+//
+//	index.json                               the Summary
+//	pods/<pod>/pod.yaml                      the pod manifest
+//	pods/<pod>/<container>/current.log       the current container logs
+//	pods/<pod>/<container>/previous.log      the logs of the previous container instance
+//	owners/<kind>-<name>.yaml                the owner manifests, e.g. ReplicaSet and Deployment
+//	events.yaml                              the events about the pods and owners
+//	nodes/<node>.yaml                        the hosting node conditions
+func Gather(
+	ctx context.Context,
+	client kubernetes.Interface,
+	namespace string,
+	w io.Writer,
+	options ...Option,
+) (*Summary, error) {
+	g := &gatherer{
+		logger:      logger.NoOp,
+		now:         time.Now,
+		client:      client,
+		namespace:   namespace,
+		maxLogBytes: 10 << 20,
+		objects:     map[types.UID]struct{}{},
+	}
+	for _, opt := range options {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+	if g.logger == nil {
+		g.logger = logger.NoOp
+	}
+	g.summary = Summary{
+		Namespace:     namespace,
+		LabelSelector: g.labelSelector,
+		Since:         g.since,
+		Until:         g.until,
+		GatheredAt:    g.now(),
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: g.labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	for idx := range pods.Items {
+		pod := &pods.Items[idx]
+		g.objects[pod.UID] = struct{}{}
+		g.addObject(path.Join("pods", pod.Name, "pod.yaml"), pod, corev1.SchemeGroupVersion.WithKind("Pod"))
+	}
+	owners := g.gatherOwners(ctx, pods.Items)
+	g.gatherLogs(ctx, pods.Items, owners)
+	g.gatherEvents(ctx)
+	g.gatherNodes(ctx, pods.Items)
+
+	if err := g.writeArchive(w); err != nil {
+		return nil, err
+	}
+	return &g.summary, nil
+}
+
+// logTail keeps the latest lines within the max bytes.
+type logTail struct {
+	maxBytes int
+
+	lines []string
+	bytes int
+	// truncated tells if any line has been dropped
+	truncated bool
+}
+
+// add adds the line, dropping the oldest lines beyond the max bytes.
+// Zero max bytes means no limit.
+func (t *logTail) add(line string) {
+	t.lines = append(t.lines, line)
+	t.bytes += len(line)
+	for t.maxBytes > 0 && t.bytes > t.maxBytes && len(t.lines) > 0 {
+		t.bytes -= len(t.lines[0])
+		t.lines[0] = ""
+		t.lines = t.lines[1:]
+		t.truncated = true
+	}
+}
+
+func (t *logTail) String() string {
+	return strings.Join(t.lines, "")
+}
+
+// containerLogs collects the logs of each pod container.
+type containerLogs struct {
+	mu   sync.Mutex
+	logs map[string]*logTail
+}
+
+func containerLogsKey(pod string, container string) string {
+	return pod + "/" + container
+}
+
+func (g *gatherer) streamLogs(ctx context.Context, container string, previous bool) *containerLogs {
+	collected := &containerLogs{logs: map[string]*logTail{}}
+
+	options := []podstream.Option{
+		podstream.WithLogger(g.logger),
+		podstream.FromSelectedPods(g.labelSelector),
+		podstream.FromContainer(container),
+		podstream.ConsumeLogsWithFunc(func(logs []podstream.LogEntry) {
+			collected.mu.Lock()
+			defer collected.mu.Unlock()
+
+			for _, entry := range logs {
+				if !g.inTimeRange(entry.Time) {
+					continue
+				}
+				key := containerLogsKey(entry.Pod, container)
+				tail, exists := collected.logs[key]
+				if !exists {
+					tail = &logTail{maxBytes: g.maxLogBytes}
+					collected.logs[key] = tail
+				}
+				tail.add(fmt.Sprintf("%s %s\n", entry.Time.Format(time.RFC3339Nano), entry.Log))
+			}
+		}),
+	}
+	if !g.since.IsZero() {
+		options = append(options, podstream.SinceTime(g.since))
+	}
+	if previous {
+		options = append(options, podstream.FromPreviousContainer())
+	}
+
+	if err := podstream.Stream(ctx.Done(), g.client.CoreV1().Pods(g.namespace), options...); err != nil {
+		g.addError("stream logs of container %s: %s", container, err)
+	}
+	return collected
+}
+
+func (g *gatherer) gatherLogs(ctx context.Context, pods []corev1.Pod, owners map[types.UID][]string) {
+	// pods stream logs of one container at a time, so stream by container names
+	var (
+		containers         []string
+		previousContainers = map[string]bool{}
+		seen               = map[string]bool{}
+	)
+	for _, pod := range pods {
+		for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				containers = append(containers, c.Name)
+			}
+		}
+		for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			if status.RestartCount > 0 {
+				previousContainers[status.Name] = true
+			}
+		}
+	}
+
+	current := map[string]*containerLogs{}
+	previous := map[string]*containerLogs{}
+	for _, container := range containers {
+		current[container] = g.streamLogs(ctx, container, false)
+		if previousContainers[container] {
+			previous[container] = g.streamLogs(ctx, container, true)
+		}
+	}
+
+	for _, pod := range pods {
+		statuses := map[string]corev1.ContainerStatus{}
+		for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			statuses[status.Name] = status
+		}
+
+		podSummary := PodSummary{
+			Name:   pod.Name,
+			Phase:  pod.Status.Phase,
+			Node:   pod.Spec.NodeName,
+			Owners: owners[pod.UID],
+		}
+		for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			status := statuses[c.Name]
+			containerSummary := ContainerSummary{
+				Name:         c.Name,
+				Ready:        status.Ready,
+				RestartCount: status.RestartCount,
+			}
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				containerSummary.LastTerminationReason = terminated.Reason
+			}
+
+			key := containerLogsKey(pod.Name, c.Name)
+			addLogs := func(collected *containerLogs, name string) string {
+				if collected == nil {
+					return ""
+				}
+				tail, exists := collected.logs[key]
+				if !exists {
+					return ""
+				}
+
+				containerSummary.Truncated = containerSummary.Truncated || tail.truncated
+				filePath := path.Join("pods", pod.Name, c.Name, name)
+				g.addFile(filePath, []byte(tail.String()))
+				return filePath
+			}
+			containerSummary.LogFile = addLogs(current[c.Name], "current.log")
+			containerSummary.PreviousLogFile = addLogs(previous[c.Name], "previous.log")
+
+			podSummary.Containers = append(podSummary.Containers, containerSummary)
+		}
+		g.summary.Pods = append(g.summary.Pods, podSummary)
+	}
+}
+
+func (g *gatherer) gatherEvents(ctx context.Context) {
+	events, err := g.client.CoreV1().Events(g.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.addError("list events: %s", err)
+		return
+	}
+
+	eventTime := func(event *corev1.Event) time.Time {
+		for _, t := range []time.Time{event.LastTimestamp.Time, event.EventTime.Time, event.FirstTimestamp.Time} {
+			if !t.IsZero() {
+				return t
+			}
+		}
+		return event.CreationTimestamp.Time
+	}
+
+	gathered := &corev1.EventList{}
+	gathered.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("EventList"))
+	for idx := range events.Items {
+		event := &events.Items[idx]
+		if _, exists := g.objects[event.InvolvedObject.UID]; !exists {
+			continue
+		}
+		if !g.inTimeRange(eventTime(event)) {
+			continue
+		}
+		event.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Event"))
+		gathered.Items = append(gathered.Items, *event)
+	}
+	sort.SliceStable(gathered.Items, func(i, j int) bool {
+		return eventTime(&gathered.Items[i]).Before(eventTime(&gathered.Items[j]))
+	})
+
+	g.summary.Events = len(gathered.Items)
+	g.addObject("events.yaml", gathered, corev1.SchemeGroupVersion.WithKind("EventList"))
+}
+
+func (g *gatherer) gatherNodes(ctx context.Context, pods []corev1.Pod) {
+	nodeNames := map[string]struct{}{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			nodeNames[pod.Spec.NodeName] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(nodeNames))
+	for name := range nodeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node, err := g.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			g.addError("get node %s: %s", name, err)
+			continue
+		}
+
+		nodeSummary := NodeSummary{Name: name, Conditions: node.Status.Conditions}
+		data, err := yaml.Marshal(nodeSummary)
+		if err != nil {
+			g.addError("encode node %s: %s", name, err)
+			continue
+		}
+		g.addFile(path.Join("nodes", name+".yaml"), data)
+		g.summary.Nodes = append(g.summary.Nodes, nodeSummary)
+	}
+}
+
+func (g *gatherer) writeArchive(w io.Writer) error {
+	index, err := json.MarshalIndent(g.summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode summary: %w", err)
+	}
+	files := append([]file{{name: "index.json", data: index}}, g.files...)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	modTime := g.summary.GatheredAt
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    f.name,
+			Mode:    0o644,
+			Size:    int64(len(f.data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		b, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		files[header.Name] = string(b)
+	}
+	return files
+}
+
+func TestGather(t *testing.T) {
+	ctx := context.Background()
+
+	isController := true
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web", UID: "deploy-uid"},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "web-abc",
+			UID:       "rs-uid",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "deploy-uid", Controller: &isController,
+			}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "web-abc-1",
+			UID:       "pod-uid",
+			Labels:    map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-abc", UID: "rs-uid", Controller: &isController,
+			}},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "app"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "app",
+				RestartCount: 2,
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"},
+				},
+			}},
+		},
+	}
+	otherPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "other",
+			UID:       "other-uid",
+			Labels:    map[string]string{"app": "other"},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue}},
+		},
+	}
+	now := metav1.NewTime(time.Now())
+	podEvent := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "pod-event"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod.Name, UID: pod.UID},
+		Reason:         "BackOff",
+		LastTimestamp:  now,
+	}
+	otherEvent := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "other-event"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: otherPod.Name, UID: otherPod.UID},
+		Reason:         "Pulled",
+		LastTimestamp:  now,
+	}
+	client := fake.NewSimpleClientset(deployment, replicaSet, pod, otherPod, node, podEvent, otherEvent)
+
+	var buf bytes.Buffer
+	summary, err := Gather(ctx, client, "ns", &buf, WithLabelSelector("app=web"), WithSince(time.Hour))
+	assert.NoError(t, err)
+
+	assert.Empty(t, summary.Errors)
+	assert.Equal(t, []string{"replicaset/web-abc", "deployment/web"}, summary.Owners)
+	assert.Equal(t, 1, summary.Events)
+	if assert.Len(t, summary.Pods, 1) {
+		podSummary := summary.Pods[0]
+		assert.Equal(t, []string{"replicaset/web-abc", "deployment/web"}, podSummary.Owners)
+		if assert.Len(t, podSummary.Containers, 1) {
+			assert.Equal(t, "OOMKilled", podSummary.Containers[0].LastTerminationReason)
+			assert.Equal(t, "pods/web-abc-1/app/current.log", podSummary.Containers[0].LogFile)
+			assert.Equal(t, "pods/web-abc-1/app/previous.log", podSummary.Containers[0].PreviousLogFile)
+		}
+	}
+	if assert.Len(t, summary.Nodes, 1) {
+		assert.Equal(t, corev1.NodeMemoryPressure, summary.Nodes[0].Conditions[0].Type)
+	}
+
+	files := readArchive(t, buf.Bytes())
+	for _, name := range []string{
+		"index.json",
+		"pods/web-abc-1/pod.yaml",
+		"pods/web-abc-1/app/current.log",
+		"pods/web-abc-1/app/previous.log",
+		"owners/replicaset-web-abc.yaml",
+		"owners/deployment-web.yaml",
+		"events.yaml",
+		"nodes/node-1.yaml",
+	} {
+		assert.Contains(t, files, name)
+	}
+	assert.NotContains(t, files, "pods/other/pod.yaml")
+
+	assert.Contains(t, files["pods/web-abc-1/pod.yaml"], "kind: Pod")
+	assert.Contains(t, files["pods/web-abc-1/app/current.log"], "fake logs")
+	assert.Contains(t, files["events.yaml"], "BackOff")
+	assert.NotContains(t, files["events.yaml"], "Pulled")
+
+	var index Summary
+	assert.NoError(t, json.Unmarshal([]byte(files["index.json"]), &index))
+	assert.Equal(t, "app=web", index.LabelSelector)
+}
+
+func TestLogTail(t *testing.T) {
+	tail := &logTail{maxBytes: 10}
+	tail.add("line 1\n")
+	assert.False(t, tail.truncated)
+	assert.Equal(t, "line 1\n", tail.String())
+
+	tail.add("line 2\n")
+	tail.add("line 3\n")
+	assert.True(t, tail.truncated)
+	assert.Equal(t, "line 3\n", tail.String())
+	assert.Equal(t, 7, tail.bytes)
+
+	unlimited := &logTail{}
+	unlimited.add("line 1\n")
+	unlimited.add("line 2\n")
+	assert.False(t, unlimited.truncated)
+	assert.Equal(t, "line 1\nline 2\n", unlimited.String())
+}
@@ -0,0 +1,64 @@
+package bundle
+
+import (
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the gatherer.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(g *gatherer) error {
+		g.logger = logger
+		return nil
+	}
+}
+
+// WithLabelSelector only gathers the pods matching the label selector.
+// Defaults to all pods in the namespace.
+func WithLabelSelector(labelSelector string) Option {
+	return func(g *gatherer) error {
+		g.labelSelector = labelSelector
+		return nil
+	}
+}
+
+// WithSince only gathers logs and events newer than the relative duration.
+func WithSince(d time.Duration) Option {
+	return func(g *gatherer) error {
+		if d <= 0 {
+			return errors.New("since duration must be positive")
+		}
+
+		g.since = g.now().Add(-d)
+		return nil
+	}
+}
+
+// WithTimeRange only gathers logs and events within the time range.
+// A zero until means no end time.
+func WithTimeRange(since time.Time, until time.Time) Option {
+	return func(g *gatherer) error {
+		if !until.IsZero() && !since.Before(until) {
+			return errors.New("since must be before until")
+		}
+
+		g.since = since
+		g.until = until
+		return nil
+	}
+}
+
+// WithMaxLogBytes keeps the latest bytes of each container logs within the size.
+// Defaults to 10MiB. Zero means no limit.
+func WithMaxLogBytes(n int) Option {
+	return func(g *gatherer) error {
+		if n < 0 {
+			return errors.New("max log bytes must not be negative")
+		}
+
+		g.maxLogBytes = n
+		return nil
+	}
+}
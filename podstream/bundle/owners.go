@@ -0,0 +1,94 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// getOwner gets the owner object. It returns the object, its group version kind and
+// its owner references.
+func (g *gatherer) getOwner(
+	ctx context.Context,
+	ref metav1.OwnerReference,
+) (runtime.Object, schema.GroupVersionKind, []metav1.OwnerReference, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, schema.GroupVersionKind{}, nil, err
+	}
+	gvk := gv.WithKind(ref.Kind)
+
+	var obj interface {
+		runtime.Object
+		GetOwnerReferences() []metav1.OwnerReference
+	}
+	switch {
+	case gv.Group == "apps" && ref.Kind == "ReplicaSet":
+		obj, err = g.client.AppsV1().ReplicaSets(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	case gv.Group == "apps" && ref.Kind == "Deployment":
+		obj, err = g.client.AppsV1().Deployments(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	case gv.Group == "apps" && ref.Kind == "StatefulSet":
+		obj, err = g.client.AppsV1().StatefulSets(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	case gv.Group == "apps" && ref.Kind == "DaemonSet":
+		obj, err = g.client.AppsV1().DaemonSets(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	case gv.Group == "batch" && ref.Kind == "Job":
+		obj, err = g.client.BatchV1().Jobs(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	case gv.Group == "batch" && ref.Kind == "CronJob":
+		obj, err = g.client.BatchV1().CronJobs(g.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	default:
+		return nil, gvk, nil, fmt.Errorf("unsupported owner kind %s", gvk)
+	}
+	if err != nil {
+		return nil, gvk, nil, err
+	}
+	return obj, gvk, obj.GetOwnerReferences(), nil
+}
+
+// gatherOwners gathers the owners of the pods recursively, and returns the owners of each pod
+// in the form of "kind/name".
+func (g *gatherer) gatherOwners(ctx context.Context, pods []corev1.Pod) map[types.UID][]string {
+	// resolved maps owner UID to the owner and its owners
+	resolved := map[types.UID][]string{}
+
+	var resolve func(ref metav1.OwnerReference) []string
+	resolve = func(ref metav1.OwnerReference) []string {
+		if owners, exists := resolved[ref.UID]; exists {
+			return owners
+		}
+
+		owner := strings.ToLower(ref.Kind) + "/" + ref.Name
+		owners := []string{owner}
+		// mark as resolved before recursion, in case of owner cycles
+		resolved[ref.UID] = owners
+
+		obj, gvk, refs, err := g.getOwner(ctx, ref)
+		if err != nil {
+			g.addError("get owner %s: %s", owner, err)
+			return owners
+		}
+		g.objects[ref.UID] = struct{}{}
+		g.summary.Owners = append(g.summary.Owners, owner)
+		g.addObject(path.Join("owners", strings.ToLower(ref.Kind)+"-"+ref.Name+".yaml"), obj, gvk)
+
+		for _, parent := range refs {
+			owners = append(owners, resolve(parent)...)
+		}
+		resolved[ref.UID] = owners
+		return owners
+	}
+
+	rv := map[types.UID][]string{}
+	for _, pod := range pods {
+		for _, ref := range pod.OwnerReferences {
+			rv[pod.UID] = append(rv[pod.UID], resolve(ref)...)
+		}
+	}
+	return rv
+}
@@ -0,0 +1,74 @@
+package bundle
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerSummary summarizes a gathered container.
+type ContainerSummary struct {
+	// Name is the container name.
+	Name string `json:"name"`
+	// Ready tells if the container is ready.
+	Ready bool `json:"ready"`
+	// RestartCount is the number of container restarts.
+	RestartCount int32 `json:"restartCount"`
+	// LastTerminationReason is the reason of the last container termination, e.g. OOMKilled.
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	// LogFile is the path of the current logs in the bundle.
+	LogFile string `json:"logFile,omitempty"`
+	// PreviousLogFile is the path of the previous logs in the bundle.
+	PreviousLogFile string `json:"previousLogFile,omitempty"`
+	// Truncated tells if the logs are truncated to the max log bytes.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PodSummary summarizes a gathered pod.
+type PodSummary struct {
+	// Name is the pod name.
+	Name string `json:"name"`
+	// Phase is the pod phase.
+	Phase corev1.PodPhase `json:"phase"`
+	// Node is the name of the node hosting the pod.
+	Node string `json:"node,omitempty"`
+	// Owners lists the owners of the pod in the form of "kind/name".
+	Owners []string `json:"owners,omitempty"`
+	// Containers lists the containers of the pod.
+	Containers []ContainerSummary `json:"containers"`
+}
+
+// NodeSummary summarizes a node hosting the gathered pods.
+type NodeSummary struct {
+	// Name is the node name.
+	Name string `json:"name"`
+	// Conditions lists the node conditions.
+	Conditions []corev1.NodeCondition `json:"conditions"`
+}
+
+// Summary is the index of a bundle.
+type Summary struct {
+	// Namespace is the namespace gathered from.
+	Namespace string `json:"namespace"`
+	// LabelSelector is the pods label selector. Empty selects all pods in the namespace.
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Since is the start time of the gathered logs and events.
+	Since time.Time `json:"since,omitempty"`
+	// Until is the end time of the gathered logs and events.
+	Until time.Time `json:"until,omitempty"`
+	// GatheredAt is the time the bundle is gathered.
+	GatheredAt time.Time `json:"gatheredAt"`
+	// Pods lists the gathered pods.
+	Pods []PodSummary `json:"pods"`
+	// Owners lists the gathered owners in the form of "kind/name".
+	Owners []string `json:"owners,omitempty"`
+	// Nodes lists the nodes hosting the pods.
+	Nodes []NodeSummary `json:"nodes,omitempty"`
+	// Events is the number of gathered events.
+	Events int `json:"events"`
+	// Errors lists the errors of items failed to gather.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Option specifies options for configuring the gatherer.
+type Option func(g *gatherer) error
@@ -0,0 +1,241 @@
+package podstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckpointKey identifies a pod container log stream.
+type CheckpointKey struct {
+	// Namespace is the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Pod is the pod name.
+	Pod string `json:"pod"`
+	// Container is the container name. It is empty when the container cannot be determined.
+	Container string `json:"container,omitempty"`
+}
+
+// Checkpoint is the position of the last emitted line of a pod container log stream.
+type Checkpoint struct {
+	// Time is the time of the last emitted line.
+	Time time.Time `json:"time"`
+	// Hashes are the hashes of the emitted lines at Time, to tell them apart from the
+	// lines not emitted yet at the same time.
+	Hashes []uint64 `json:"hashes"`
+}
+
+// seen tells if the line at the time has been emitted before the checkpoint.
+func (c Checkpoint) seen(at time.Time, log string) bool {
+	if at.Before(c.Time) {
+		return true
+	}
+	if !at.Equal(c.Time) {
+		return false
+	}
+
+	hash := lineHash(log)
+	for _, h := range c.Hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the checkpoint to the line at the time.
+func (c Checkpoint) advance(at time.Time, log string) Checkpoint {
+	switch {
+	case at.After(c.Time):
+		return Checkpoint{Time: at, Hashes: []uint64{lineHash(log)}}
+	case at.Equal(c.Time):
+		return Checkpoint{Time: c.Time, Hashes: append(c.Hashes[:len(c.Hashes):len(c.Hashes)], lineHash(log))}
+	default:
+		return c
+	}
+}
+
+func lineHash(log string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(log))
+	return h.Sum64()
+}
+
+// CheckpointStore persists the checkpoints of log streams.
+type CheckpointStore interface {
+	// LoadCheckpoint returns the checkpoint of the log stream, or false if not found.
+	LoadCheckpoint(key CheckpointKey) (Checkpoint, bool, error)
+	// SaveCheckpoints saves the checkpoints of the log streams.
+	SaveCheckpoints(checkpoints map[CheckpointKey]Checkpoint) error
+}
+
+// FileCheckpointStore stores checkpoints in a JSON file.
+type FileCheckpointStore struct {
+	path string
+
+	mu          sync.Mutex
+	checkpoints map[CheckpointKey]Checkpoint
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// fileCheckpoint is the checkpoint record in the file.
+type fileCheckpoint struct {
+	CheckpointKey
+	Checkpoint
+}
+
+// NewFileCheckpointStore creates a checkpoint store persisting to the file.
+// Checkpoints saved previously in the file are loaded.
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	store := &FileCheckpointStore{
+		path:        path,
+		checkpoints: map[CheckpointKey]Checkpoint{},
+	}
+
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return store, nil
+	case err != nil:
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+
+	var records []fileCheckpoint
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("decode checkpoints %s: %w", path, err)
+	}
+	for _, record := range records {
+		store.checkpoints[record.CheckpointKey] = record.Checkpoint
+	}
+
+	return store, nil
+}
+
+func (s *FileCheckpointStore) LoadCheckpoint(key CheckpointKey) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoint, exists := s.checkpoints[key]
+	return checkpoint, exists, nil
+}
+
+func (s *FileCheckpointStore) SaveCheckpoints(checkpoints map[CheckpointKey]Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, checkpoint := range checkpoints {
+		s.checkpoints[key] = checkpoint
+	}
+
+	records := make([]fileCheckpoint, 0, len(s.checkpoints))
+	for key, checkpoint := range s.checkpoints {
+		records = append(records, fileCheckpoint{CheckpointKey: key, Checkpoint: checkpoint})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i].CheckpointKey, records[j].CheckpointKey
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+	b, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("encode checkpoints: %w", err)
+	}
+
+	// write to a temporary file then rename, so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create checkpoints file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace checkpoints file: %w", err)
+	}
+	return nil
+}
+
+// loadCheckpoint loads the checkpoint of the log stream. It returns false if checkpointing
+// is disabled or the checkpoint is not found.
+func (s *Streamer) loadCheckpoint(key CheckpointKey) (Checkpoint, bool) {
+	if s.checkpointStore == nil {
+		return Checkpoint{}, false
+	}
+
+	checkpoint, found, err := s.checkpointStore.LoadCheckpoint(key)
+	if err != nil {
+		s.logger.Log("failed to load checkpoint of pod %s: %s", key.Pod, err)
+		return Checkpoint{}, false
+	}
+	return checkpoint, found
+}
+
+// checkpointTracker tracks the checkpoints of the log streams being emitted.
+type checkpointTracker struct {
+	store CheckpointStore
+
+	checkpoints map[CheckpointKey]Checkpoint
+	// changed tracks the checkpoints changed since last save
+	changed map[CheckpointKey]struct{}
+}
+
+func newCheckpointTracker(store CheckpointStore) *checkpointTracker {
+	return &checkpointTracker{
+		store:       store,
+		checkpoints: map[CheckpointKey]Checkpoint{},
+		changed:     map[CheckpointKey]struct{}{},
+	}
+}
+
+// observe advances the checkpoints with the logs read from the pods.
+func (t *checkpointTracker) observe(logs []LogEntry) {
+	for _, log := range logs {
+		if log.Kind != EntryKindLog {
+			continue
+		}
+
+		key := CheckpointKey{Namespace: log.Namespace, Pod: log.Pod, Container: log.Container}
+		checkpoint, exists := t.checkpoints[key]
+		if !exists {
+			// continue from the stored checkpoint to keep the hashes of the lines at the same time,
+			// load failures have been reported when resuming the log stream
+			checkpoint, _, _ = t.store.LoadCheckpoint(key)
+		}
+		t.checkpoints[key] = checkpoint.advance(log.Time, log.Log)
+		t.changed[key] = struct{}{}
+	}
+}
+
+// save saves the changed checkpoints to the store.
+func (t *checkpointTracker) save() error {
+	if len(t.changed) < 1 {
+		return nil
+	}
+
+	checkpoints := make(map[CheckpointKey]Checkpoint, len(t.changed))
+	for key := range t.changed {
+		checkpoints[key] = t.checkpoints[key]
+	}
+	if err := t.store.SaveCheckpoints(checkpoints); err != nil {
+		return err
+	}
+	t.changed = map[CheckpointKey]struct{}{}
+	return nil
+}
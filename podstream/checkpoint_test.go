@@ -0,0 +1,126 @@
+package podstream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	checkpoint := Checkpoint{}.advance(at, "a")
+	checkpoint = checkpoint.advance(at, "b")
+	checkpoint = checkpoint.advance(at.Add(-time.Second), "c")
+	assert.Equal(t, at, checkpoint.Time)
+	assert.Len(t, checkpoint.Hashes, 2)
+
+	assert.True(t, checkpoint.seen(at.Add(-time.Millisecond), "c"))
+	assert.True(t, checkpoint.seen(at, "a"))
+	assert.True(t, checkpoint.seen(at, "b"))
+	assert.False(t, checkpoint.seen(at, "d"), "lines at the same time not emitted yet")
+	assert.False(t, checkpoint.seen(at.Add(time.Millisecond), "a"))
+
+	checkpoint = checkpoint.advance(at.Add(time.Second), "d")
+	assert.Equal(t, Checkpoint{Time: at.Add(time.Second), Hashes: []uint64{lineHash("d")}}, checkpoint)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := CheckpointKey{Namespace: "ns", Pod: "pod-a", Container: "app"}
+
+	store, err := NewFileCheckpointStore(path)
+	assert.NoError(t, err)
+	_, found, err := store.LoadCheckpoint(key)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	checkpoint := Checkpoint{}.advance(at, "a")
+	assert.NoError(t, store.SaveCheckpoints(map[CheckpointKey]Checkpoint{key: checkpoint}))
+
+	// reopen to load from the file
+	store, err = NewFileCheckpointStore(path)
+	assert.NoError(t, err)
+	loaded, found, err := store.LoadCheckpoint(key)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, loaded.Time.Equal(at))
+	assert.Equal(t, checkpoint.Hashes, loaded.Hashes)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files should be cleaned up")
+
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = NewFileCheckpointStore(path)
+	assert.Error(t, err)
+}
+
+func TestCheckpointTracker(t *testing.T) {
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := CheckpointKey{Namespace: "ns", Pod: "pod-a"}
+
+	store, err := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, store.SaveCheckpoints(map[CheckpointKey]Checkpoint{
+		key: Checkpoint{}.advance(at, "a"),
+	}))
+
+	tracker := newCheckpointTracker(store)
+	tracker.observe([]LogEntry{
+		{Time: at, Log: "b", Namespace: "ns", Pod: "pod-a"},
+		{Kind: EntryKindEvent, Time: at.Add(time.Second), Log: "event", Namespace: "ns", Pod: "pod-a"},
+	})
+	assert.NoError(t, tracker.save())
+
+	checkpoint, found, err := store.LoadCheckpoint(key)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, at, checkpoint.Time, "events should not move the checkpoint")
+	assert.True(t, checkpoint.seen(at, "a"), "stored hashes should be kept")
+	assert.True(t, checkpoint.seen(at, "b"))
+}
+
+func TestStreamer_Checkpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+
+	run := func() *streamerTestCtx {
+		testCtx := newBaseStreamerTestCtx(t, CheckpointToFile(path))
+		createTestPod(t, testCtx, "pod-a", testCtx.labels)
+		assert.NoError(t, testCtx.streamer.Run(make(chan struct{})))
+		return testCtx
+	}
+	logOptions := func(testCtx *streamerTestCtx) *corev1.PodLogOptions {
+		for _, action := range testCtx.fakeKubeClient.Actions() {
+			if action.GetSubresource() == "log" {
+				return action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+			}
+		}
+		return nil
+	}
+
+	testCtx := run()
+	if opts := logOptions(testCtx); assert.NotNil(t, opts) {
+		assert.Nil(t, opts.SinceTime)
+	}
+
+	store, err := NewFileCheckpointStore(path)
+	assert.NoError(t, err)
+	checkpoint, found, err := store.LoadCheckpoint(CheckpointKey{Namespace: testCtx.namespace, Pod: "pod-a"})
+	assert.NoError(t, err)
+	if assert.True(t, found) {
+		assert.Equal(t, []uint64{lineHash("fake logs")}, checkpoint.Hashes)
+	}
+
+	// the restarted stream resumes from the checkpoint
+	testCtx = run()
+	if opts := logOptions(testCtx); assert.NotNil(t, opts) && assert.NotNil(t, opts.SinceTime) {
+		assert.True(t, checkpoint.Time.Equal(opts.SinceTime.Time))
+	}
+}
@@ -11,3 +11,36 @@ func (s LogEntryConsumers) OnLogs(logs []LogEntry) {
 		c.OnLogs(logs)
 	}
 }
+
+// LogEntryTransformers chains a group of LogEntryTransformer.
+// Each transformer is called with the output of the previous one with the slice order.
+type LogEntryTransformers []LogEntryTransformer
+
+var _ LogEntryTransformer = (*LogEntryTransformers)(nil)
+
+func (s LogEntryTransformers) TransformLogs(logs []LogEntry) []LogEntry {
+	for _, t := range s {
+		if len(logs) < 1 {
+			return logs
+		}
+		logs = t.TransformLogs(logs)
+	}
+	return logs
+}
+
+var _ LogEntryFlusher = (*LogEntryTransformers)(nil)
+
+// FlushLogs flushes the transformers with the slice order. Logs flushed by a transformer
+// are transformed by the transformers after it.
+func (s LogEntryTransformers) FlushLogs() []LogEntry {
+	var logs []LogEntry
+	for _, t := range s {
+		if len(logs) > 0 {
+			logs = t.TransformLogs(logs)
+		}
+		if f, ok := t.(LogEntryFlusher); ok {
+			logs = append(logs, f.FlushLogs()...)
+		}
+	}
+	return logs
+}
@@ -29,3 +29,63 @@ func TestLogEntryConsumers(t *testing.T) {
 
 	assert.Len(t, loadedLogs, 2)
 }
+
+func TestLogEntryTransformers(t *testing.T) {
+	transformers := LogEntryTransformers{
+		LogEntryTransformerFunc(func(logs []LogEntry) []LogEntry {
+			for idx := range logs {
+				logs[idx].Log += "-a"
+			}
+			return logs
+		}),
+		LogEntryTransformerFunc(func(logs []LogEntry) []LogEntry {
+			return logs[:1]
+		}),
+	}
+
+	logs := transformers.TransformLogs([]LogEntry{
+		{Time: time.Now(), Log: "foo"},
+		{Time: time.Now(), Log: "bar"},
+	})
+
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "foo-a", logs[0].Log)
+	}
+}
+
+type heldLogsTransformer struct {
+	held []LogEntry
+}
+
+func (t *heldLogsTransformer) TransformLogs(logs []LogEntry) []LogEntry {
+	t.held = append(t.held, logs...)
+	return nil
+}
+
+func (t *heldLogsTransformer) FlushLogs() []LogEntry {
+	logs := t.held
+	t.held = nil
+	return logs
+}
+
+func TestLogEntryTransformers_FlushLogs(t *testing.T) {
+	held := &heldLogsTransformer{}
+	transformers := LogEntryTransformers{
+		held,
+		LogEntryTransformerFunc(func(logs []LogEntry) []LogEntry {
+			for idx := range logs {
+				logs[idx].Log += "-a"
+			}
+			return logs
+		}),
+	}
+
+	logs := transformers.TransformLogs([]LogEntry{{Time: time.Now(), Log: "foo"}})
+	assert.Empty(t, logs)
+
+	logs = transformers.FlushLogs()
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "foo-a", logs[0].Log)
+	}
+	assert.Empty(t, transformers.FlushLogs())
+}
@@ -0,0 +1,129 @@
+package correlate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+var (
+	defaultKeyFields = []string{
+		"trace_id", "traceId", "traceID", "trace.id",
+		"request_id", "requestId", "requestID", "request.id", "x-request-id",
+	}
+
+	// traceparentPattern matches W3C traceparent values.
+	traceparentPattern = regexp.MustCompile(`\b00-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}\b`)
+)
+
+// jsonFields extracts the key from fields of JSON formatted logs.
+type jsonFields []string
+
+func (fields jsonFields) ExtractKey(entry podstream.LogEntry) (string, bool) {
+	trimmed := strings.TrimSpace(entry.Log)
+	if !strings.HasPrefix(trimmed, "{") {
+		return "", false
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmed), &values); err != nil {
+		return "", false
+	}
+	for _, field := range fields {
+		switch v := values[field].(type) {
+		case string:
+			if v != "" {
+				return v, true
+			}
+		case float64:
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+// JSONFields creates a KeyExtractor reading the key from the first present field of
+// JSON formatted logs.
+func JSONFields(fields ...string) KeyExtractor {
+	return jsonFields(fields)
+}
+
+// regexpExtractor extracts the key with a regular expression.
+type regexpExtractor struct {
+	pattern *regexp.Regexp
+	group   int
+}
+
+func (e *regexpExtractor) ExtractKey(entry podstream.LogEntry) (string, bool) {
+	m := e.pattern.FindStringSubmatch(entry.Log)
+	if m == nil || m[e.group] == "" {
+		return "", false
+	}
+	return m[e.group], true
+}
+
+// Regexp creates a KeyExtractor reading the key with a regular expression. The key is the
+// named group "key" if present, otherwise the first group, otherwise the whole match.
+func Regexp(expr string) (KeyExtractor, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile key pattern %q: %w", expr, err)
+	}
+
+	group := 0
+	if idx := pattern.SubexpIndex("key"); idx > 0 {
+		group = idx
+	} else if pattern.NumSubexp() > 0 {
+		group = 1
+	}
+	return &regexpExtractor{pattern: pattern, group: group}, nil
+}
+
+// KeyValueFields creates a KeyExtractor reading the key from key-value formatted fields,
+// e.g. request_id=abc or "traceId": "abc".
+func KeyValueFields(fields ...string) KeyExtractor {
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+
+	return &regexpExtractor{
+		pattern: regexp.MustCompile(
+			`(?:^|[\s,{])"?(?:` + strings.Join(quoted, "|") + `)"?\s*[=:]\s*"?([\w.:-]+)`,
+		),
+		group: 1,
+	}
+}
+
+// Traceparent creates a KeyExtractor reading the trace ID from W3C traceparent values.
+func Traceparent() KeyExtractor {
+	return &regexpExtractor{pattern: traceparentPattern, group: 1}
+}
+
+// KeyExtractors tries a group of KeyExtractor one by one with the slice order,
+// the first found key is used.
+type KeyExtractors []KeyExtractor
+
+var _ KeyExtractor = (KeyExtractors)(nil)
+
+func (s KeyExtractors) ExtractKey(entry podstream.LogEntry) (string, bool) {
+	for _, e := range s {
+		if key, ok := e.ExtractKey(entry); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// DefaultKeyExtractor reads common trace ID and request ID fields from JSON and key-value
+// formatted logs, and trace IDs from W3C traceparent values.
+func DefaultKeyExtractor() KeyExtractor {
+	return KeyExtractors{
+		JSONFields(defaultKeyFields...),
+		KeyValueFields(defaultKeyFields...),
+		Traceparent(),
+	}
+}
@@ -0,0 +1,229 @@
+package correlate
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// keyEntries holds the entries of a correlation key.
+type keyEntries struct {
+	key      string
+	entries  []podstream.LogEntry
+	lastSeen time.Time
+
+	// element is the element of the key in the recently seen list.
+	element *list.Element
+}
+
+// follower follows the entries of a correlation key.
+type follower struct {
+	key     string
+	handler Handler
+}
+
+// Index is a podstream.LogEntryConsumer that indexes log entries by their correlation key,
+// e.g. trace ID or request ID, across all streamed pods.
+//
+// Index also implements podstream.LogEntryTransformer, which indexes the logs and passes
+// them through, so it can be used as a stage before other consumers.
+type Index struct {
+	logger logger.Logger
+
+	extractor KeyExtractor
+
+	// maxKeys limits the number of keys kept. Least recently seen keys are evicted first.
+	maxKeys int
+	// maxEntriesPerKey limits the number of entries kept per key. Oldest entries are dropped first.
+	maxEntriesPerKey int
+	// retention evicts keys not seen within the duration, relative to the latest entry time.
+	retention time.Duration
+
+	mu         sync.Mutex
+	keys       map[string]*keyEntries
+	recent     *list.List
+	latest     time.Time
+	followers  map[int]*follower
+	followerID int
+}
+
+var (
+	_ podstream.LogEntryConsumer    = (*Index)(nil)
+	_ podstream.LogEntryTransformer = (*Index)(nil)
+)
+
+// New creates a correlation index.
+func New(options ...Option) (*Index, error) {
+	index := &Index{
+		logger:           logger.NoOp,
+		extractor:        DefaultKeyExtractor(),
+		maxKeys:          10000,
+		maxEntriesPerKey: 1000,
+		retention:        1 * time.Hour,
+		keys:             map[string]*keyEntries{},
+		recent:           list.New(),
+		followers:        map[int]*follower{},
+	}
+	for _, opt := range options {
+		if err := opt(index); err != nil {
+			return nil, err
+		}
+	}
+	if index.logger == nil {
+		index.logger = logger.NoOp
+	}
+
+	return index, nil
+}
+
+// OnLogs indexes the logs.
+func (index *Index) OnLogs(logs []podstream.LogEntry) {
+	index.index(logs)
+}
+
+// TransformLogs indexes the logs and returns them as is.
+func (index *Index) TransformLogs(logs []podstream.LogEntry) []podstream.LogEntry {
+	index.index(logs)
+	return logs
+}
+
+func (index *Index) index(logs []podstream.LogEntry) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	matched := map[string][]podstream.LogEntry{}
+	for _, entry := range logs {
+		key, ok := index.extractor.ExtractKey(entry)
+		if !ok {
+			continue
+		}
+
+		index.add(key, entry)
+		matched[key] = append(matched[key], entry)
+	}
+	index.evict()
+
+	if len(matched) < 1 || len(index.followers) < 1 {
+		return
+	}
+	// deliver in follow order, so handlers are called in the same sequence between calls
+	ids := make([]int, 0, len(index.followers))
+	for id := range index.followers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		f := index.followers[id]
+		if entries, ok := matched[f.key]; ok {
+			f.handler(entries)
+		}
+	}
+}
+
+func (index *Index) add(key string, entry podstream.LogEntry) {
+	ke, exists := index.keys[key]
+	if !exists {
+		ke = &keyEntries{key: key}
+		ke.element = index.recent.PushBack(ke)
+		index.keys[key] = ke
+	} else {
+		index.recent.MoveToBack(ke.element)
+	}
+
+	ke.entries = append(ke.entries, entry)
+	if index.maxEntriesPerKey > 0 && len(ke.entries) > index.maxEntriesPerKey {
+		ke.entries = ke.entries[len(ke.entries)-index.maxEntriesPerKey:]
+	}
+	if entry.Time.After(ke.lastSeen) {
+		ke.lastSeen = entry.Time
+	}
+	if entry.Time.After(index.latest) {
+		index.latest = entry.Time
+	}
+}
+
+func (index *Index) remove(ke *keyEntries) {
+	index.recent.Remove(ke.element)
+	delete(index.keys, ke.key)
+}
+
+func (index *Index) evict() {
+	for index.maxKeys > 0 && len(index.keys) > index.maxKeys {
+		ke := index.recent.Front().Value.(*keyEntries)
+		index.logger.Log("evicting key %q: too many keys", ke.key)
+		index.remove(ke)
+	}
+
+	if index.retention <= 0 {
+		return
+	}
+	expiredBefore := index.latest.Add(-index.retention)
+	for e := index.recent.Front(); e != nil; {
+		next := e.Next()
+		if ke := e.Value.(*keyEntries); ke.lastSeen.Before(expiredBefore) {
+			index.remove(ke)
+		}
+		e = next
+	}
+}
+
+func sortedEntries(entries []podstream.LogEntry) []podstream.LogEntry {
+	rv := append([]podstream.LogEntry{}, entries...)
+	sort.SliceStable(rv, func(i, j int) bool {
+		return rv[i].Time.Before(rv[j].Time)
+	})
+	return rv
+}
+
+// Lookup returns the indexed entries of the key in time order across pods.
+func (index *Index) Lookup(key string) []podstream.LogEntry {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	ke, exists := index.keys[key]
+	if !exists {
+		return nil
+	}
+	return sortedEntries(ke.entries)
+}
+
+// Keys returns the indexed keys, most recently seen first.
+func (index *Index) Keys() []string {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	keys := make([]string, 0, len(index.keys))
+	for e := index.recent.Back(); e != nil; e = e.Prev() {
+		keys = append(keys, e.Value.(*keyEntries).key)
+	}
+	return keys
+}
+
+// Follow calls the handler with the indexed entries of the key in time order, then with
+// the new entries of the key as they are indexed. The returned function stops following.
+//
+// New entries are delivered in the order they are consumed, which is in time order
+// within each pod. The handler is called with the index locked, so it must not call
+// back into the index.
+func (index *Index) Follow(key string, handler Handler) (stop func()) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if ke, exists := index.keys[key]; exists {
+		handler(sortedEntries(ke.entries))
+	}
+	index.followerID++
+	id := index.followerID
+	index.followers[id] = &follower{key: key, handler: handler}
+
+	return func() {
+		index.mu.Lock()
+		defer index.mu.Unlock()
+
+		delete(index.followers, id)
+	}
+}
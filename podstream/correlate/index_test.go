@@ -0,0 +1,121 @@
+package correlate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultKeyExtractor(t *testing.T) {
+	extractor := DefaultKeyExtractor()
+
+	cases := map[string]string{
+		`{"msg":"handled","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"}`:                  "4bf92f3577b34da6a3ce929d0e0e4736",
+		`{"msg":"handled","requestId":"req-1"}`:                                            "req-1",
+		`level=info msg=handled request_id=req-2 took=3ms`:                                 "req-2",
+		`GET /api traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 200`: "4bf92f3577b34da6a3ce929d0e0e4736",
+	}
+	for log, expected := range cases {
+		key, ok := extractor.ExtractKey(podstream.LogEntry{Log: log})
+		assert.True(t, ok, log)
+		assert.Equal(t, expected, key, log)
+	}
+
+	_, ok := extractor.ExtractKey(podstream.LogEntry{Log: "server started"})
+	assert.False(t, ok)
+}
+
+func TestRegexp(t *testing.T) {
+	extractor, err := Regexp(`order (?P<key>#\d+)`)
+	assert.NoError(t, err)
+
+	key, ok := extractor.ExtractKey(podstream.LogEntry{Log: "shipping order #42 to warehouse"})
+	assert.True(t, ok)
+	assert.Equal(t, "#42", key)
+
+	_, err = Regexp(`(`)
+	assert.Error(t, err)
+}
+
+func TestIndex(t *testing.T) {
+	index, err := New(WithKeyFields("request_id"))
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	// logs from the frontend pod
+	index.OnLogs([]podstream.LogEntry{
+		{Time: start, Pod: "frontend", Log: "request_id=r1 received"},
+		{Time: start.Add(3 * time.Second), Pod: "frontend", Log: "request_id=r1 responded"},
+		{Time: start.Add(4 * time.Second), Pod: "frontend", Log: "request_id=r2 received"},
+	})
+
+	var followed []string
+	stop := index.Follow("r1", func(logs []podstream.LogEntry) {
+		for _, entry := range logs {
+			followed = append(followed, entry.Pod+": "+entry.Log)
+		}
+	})
+
+	// logs from the backend pod arrive later
+	logs := index.TransformLogs([]podstream.LogEntry{
+		{Time: start.Add(1 * time.Second), Pod: "backend", Log: "request_id=r1 querying"},
+		{Time: start.Add(2 * time.Second), Pod: "backend", Log: "no request id"},
+	})
+	assert.Len(t, logs, 2, "logs should be passed through")
+
+	var lookup []string
+	for _, entry := range index.Lookup("r1") {
+		lookup = append(lookup, entry.Pod+": "+entry.Log)
+	}
+	assert.Equal(t, []string{
+		"frontend: request_id=r1 received",
+		"backend: request_id=r1 querying",
+		"frontend: request_id=r1 responded",
+	}, lookup)
+
+	assert.Equal(t, []string{
+		"frontend: request_id=r1 received",
+		"frontend: request_id=r1 responded",
+		"backend: request_id=r1 querying",
+	}, followed)
+
+	stop()
+	index.OnLogs([]podstream.LogEntry{{Time: start.Add(5 * time.Second), Log: "request_id=r1 done"}})
+	assert.Len(t, followed, 3)
+
+	assert.Equal(t, []string{"r1", "r2"}, index.Keys())
+	assert.Nil(t, index.Lookup("missing"))
+}
+
+func TestIndex_Eviction(t *testing.T) {
+	index, err := New(
+		WithMaxKeys(2),
+		WithMaxEntriesPerKey(2),
+		WithRetention(time.Minute),
+	)
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	index.OnLogs([]podstream.LogEntry{
+		{Time: start, Log: "request_id=a 1"},
+		{Time: start, Log: "request_id=a 2"},
+		{Time: start, Log: "request_id=a 3"},
+		{Time: start, Log: "request_id=b 1"},
+		{Time: start, Log: "request_id=c 1"},
+	})
+	assert.Equal(t, []string{"c", "b"}, index.Keys())
+
+	index.OnLogs([]podstream.LogEntry{
+		{Time: start.Add(30 * time.Second), Log: "request_id=b 2"},
+		{Time: start.Add(30 * time.Second), Log: "request_id=b 3"},
+		{Time: start.Add(30 * time.Second), Log: "request_id=b 4"},
+	})
+	if entries := index.Lookup("b"); assert.Len(t, entries, 2) {
+		assert.Equal(t, "request_id=b 3", entries[0].Log)
+	}
+
+	index.OnLogs([]podstream.LogEntry{{Time: start.Add(2 * time.Minute), Log: "request_id=d 1"}})
+	assert.Equal(t, []string{"d"}, index.Keys())
+}
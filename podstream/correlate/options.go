@@ -0,0 +1,93 @@
+package correlate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the index.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(index *Index) error {
+		index.logger = logger
+		return nil
+	}
+}
+
+// WithKeyExtractors sets the extractors to read the correlation key with. The extractors are
+// tried one by one, the first found key is used. Defaults to DefaultKeyExtractor.
+func WithKeyExtractors(first KeyExtractor, other ...KeyExtractor) Option {
+	return func(index *Index) error {
+		extractors := append(KeyExtractors{first}, other...)
+		for _, e := range extractors {
+			if e == nil {
+				return errors.New("key extractor is required")
+			}
+		}
+
+		index.extractor = extractors
+		return nil
+	}
+}
+
+// WithKeyFields reads the correlation key from the fields of JSON or key-value formatted logs.
+func WithKeyFields(first string, other ...string) Option {
+	return func(index *Index) error {
+		fields := append([]string{first}, other...)
+		index.extractor = KeyExtractors{JSONFields(fields...), KeyValueFields(fields...)}
+		return nil
+	}
+}
+
+// WithKeyRegexp reads the correlation key with a regular expression. See Regexp for details.
+func WithKeyRegexp(expr string) Option {
+	return func(index *Index) error {
+		extractor, err := Regexp(expr)
+		if err != nil {
+			return err
+		}
+
+		index.extractor = extractor
+		return nil
+	}
+}
+
+// WithMaxKeys limits the number of keys kept, least recently seen keys are evicted first.
+// Defaults to 10000. Zero means no limit.
+func WithMaxKeys(n int) Option {
+	return func(index *Index) error {
+		if n < 0 {
+			return errors.New("max keys must not be negative")
+		}
+
+		index.maxKeys = n
+		return nil
+	}
+}
+
+// WithMaxEntriesPerKey limits the number of entries kept per key, oldest entries are
+// dropped first. Defaults to 1000. Zero means no limit.
+func WithMaxEntriesPerKey(n int) Option {
+	return func(index *Index) error {
+		if n < 0 {
+			return errors.New("max entries per key must not be negative")
+		}
+
+		index.maxEntriesPerKey = n
+		return nil
+	}
+}
+
+// WithRetention evicts keys not seen within the duration, relative to the latest indexed
+// entry time. Defaults to 1 hour. Zero disables retention.
+func WithRetention(retention time.Duration) Option {
+	return func(index *Index) error {
+		if retention < 0 {
+			return errors.New("retention must not be negative")
+		}
+
+		index.retention = retention
+		return nil
+	}
+}
@@ -0,0 +1,25 @@
+package correlate
+
+import (
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// KeyExtractor extracts the correlation key from a log entry.
+type KeyExtractor interface {
+	// ExtractKey returns the correlation key of the entry.
+	// It returns false if the entry has no correlation key.
+	ExtractKey(entry podstream.LogEntry) (string, bool)
+}
+
+// KeyExtractorFunc is a function that extracts the correlation key from a log entry.
+type KeyExtractorFunc func(entry podstream.LogEntry) (string, bool)
+
+func (f KeyExtractorFunc) ExtractKey(entry podstream.LogEntry) (string, bool) {
+	return f(entry)
+}
+
+// Handler handles log entries of a followed correlation key.
+type Handler func(logs []podstream.LogEntry)
+
+// Option specifies options for configuring the index.
+type Option func(index *Index) error
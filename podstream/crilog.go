@@ -0,0 +1,543 @@
+package podstream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCRILogDir is the directory the kubelet writes the container logs to.
+const DefaultCRILogDir = "/var/log/pods"
+
+// defaultFilePollInterval is the interval to poll the log files for changes.
+const defaultFilePollInterval = 250 * time.Millisecond
+
+// StreamCRILogs streams the container logs from the CRI log files in the log directory of
+// a node, i.e. /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart count>.log, instead
+// of going through the API server. The logs go through the same pipeline as Stream.
+//
+// Like Stream, the current log file of each container is read. In follow mode, the log
+// files are tailed across rotations and container restarts, and new pods are picked up.
+// Pods label selectors don't apply to log files, while FromContainer selects the container.
+func StreamCRILogs(
+	stop <-chan struct{},
+	logDir string,
+	options ...Option,
+) error {
+	streamer, err := NewStreamer(nil, options...)
+	if err != nil {
+		return err
+	}
+
+	return streamer.streamCRILogs(stop, logDir)
+}
+
+// criLogLine is a line in the CRI log format:
+//
+//	<RFC3339Nano timestamp> <stream> <tag> <message>
+//
+// The tag is "P" for partial lines split by the runtime and "F" for full lines.
+type criLogLine struct {
+	time    time.Time
+	stream  string
+	partial bool
+	log     string
+}
+
+func parseCRILogLine(line string) (criLogLine, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return criLogLine{}, fmt.Errorf("invalid CRI log line: %q", line)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return criLogLine{}, fmt.Errorf("invalid CRI log timestamp: %w", err)
+	}
+
+	rv := criLogLine{time: timestamp, stream: parts[1]}
+	// the tag can have multiple flags separated by ":", the first one is the partial flag
+	switch flag := strings.SplitN(parts[2], ":", 2)[0]; flag {
+	case "P":
+		rv.partial = true
+	case "F":
+	default:
+		return criLogLine{}, fmt.Errorf("invalid CRI log tag: %q", parts[2])
+	}
+	if len(parts) > 3 {
+		rv.log = parts[3]
+	}
+	return rv, nil
+}
+
+// criLogJoiner joins the partial lines of the streams.
+type criLogJoiner struct {
+	pending map[string]*criLogLine
+}
+
+// join returns the full line, or false if the line is partial.
+func (j *criLogJoiner) join(line criLogLine) (criLogLine, bool) {
+	if j.pending == nil {
+		j.pending = map[string]*criLogLine{}
+	}
+
+	pending, exists := j.pending[line.stream]
+	if line.partial {
+		if exists {
+			pending.log = appendLogBounded(pending.log, line.log)
+		} else {
+			line.log = appendLogBounded("", line.log)
+			j.pending[line.stream] = &line
+		}
+		return criLogLine{}, false
+	}
+
+	if !exists {
+		return line, true
+	}
+	delete(j.pending, line.stream)
+	// the joined line is at the time of its first part
+	pending.log = appendLogBounded(pending.log, line.log)
+	pending.partial = false
+	return *pending, true
+}
+
+// flush returns the partial lines not ended yet in time order, e.g. the log file ends with a
+// partial line.
+func (j *criLogJoiner) flush() []criLogLine {
+	lines := make([]criLogLine, 0, len(j.pending))
+	for _, pending := range j.pending {
+		pending.partial = false
+		lines = append(lines, *pending)
+	}
+	j.pending = nil
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].time.Before(lines[j].time)
+	})
+	return lines
+}
+
+// appendLogBounded appends the log to the line up to maxLogLineBytes, the rest is dropped.
+func appendLogBounded(line string, log string) string {
+	room := maxLogLineBytes - len(line)
+	if room < 1 {
+		return line
+	}
+	if len(log) > room {
+		log = log[:room]
+	}
+	return line + log
+}
+
+// criLogFile is a container log file named <restart count>.log.
+type criLogFile struct {
+	path    string
+	restart int
+}
+
+// currentCRILogFile returns the current log file of the container, i.e. the log file of the
+// latest restart. Rotated log files are not considered.
+func currentCRILogFile(containerDir string) (criLogFile, bool, error) {
+	entries, err := os.ReadDir(containerDir)
+	if err != nil {
+		return criLogFile{}, false, err
+	}
+
+	var (
+		current criLogFile
+		found   bool
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		restart, err := strconv.Atoi(strings.TrimSuffix(name, ".log"))
+		if err != nil {
+			continue
+		}
+		if !found || restart > current.restart {
+			current = criLogFile{path: filepath.Join(containerDir, name), restart: restart}
+			found = true
+		}
+	}
+	return current, found, nil
+}
+
+// criContainerDir is the log directory of a pod container.
+type criContainerDir struct {
+	path string
+	key  CheckpointKey
+}
+
+// listCRIContainerDirs lists the container log directories in the log directory.
+func (s *Streamer) listCRIContainerDirs(logDir string) ([]criContainerDir, error) {
+	podDirs, err := os.ReadDir(logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []criContainerDir
+	for _, podDir := range podDirs {
+		if !podDir.IsDir() {
+			continue
+		}
+		// pod log directories are named <namespace>_<pod>_<uid>, and the names cannot contain "_"
+		parts := strings.Split(podDir.Name(), "_")
+		if len(parts) != 3 {
+			continue
+		}
+
+		containerDirs, err := os.ReadDir(filepath.Join(logDir, podDir.Name()))
+		if err != nil {
+			// the pod might have been removed
+			continue
+		}
+		for _, containerDir := range containerDirs {
+			if !containerDir.IsDir() {
+				continue
+			}
+			if s.podLogOptions.Container != "" && s.podLogOptions.Container != containerDir.Name() {
+				continue
+			}
+
+			rv = append(rv, criContainerDir{
+				path: filepath.Join(logDir, podDir.Name(), containerDir.Name()),
+				key:  CheckpointKey{Namespace: parts[0], Pod: parts[1], Container: containerDir.Name()},
+			})
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].path < rv[j].path
+	})
+	return rv, nil
+}
+
+func (s *Streamer) streamCRILogs(stop <-chan struct{}, logDir string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	if s.filePollInterval < 1 {
+		s.filePollInterval = defaultFilePollInterval
+	}
+
+	buf := make(chan LogEntry, 128)
+
+	var containerWorks sync.WaitGroup
+	knownContainers := map[string]struct{}{}
+	// trackContainers starts tailing the containers not tracked yet
+	trackContainers := func() error {
+		containerDirs, err := s.listCRIContainerDirs(logDir)
+		if err != nil {
+			err = fmt.Errorf("list container log directories: %w", err)
+			s.logger.Log(err.Error())
+			return err
+		}
+
+		for _, containerDir := range containerDirs {
+			if _, exists := knownContainers[containerDir.path]; exists {
+				continue
+			}
+			knownContainers[containerDir.path] = struct{}{}
+
+			containerWorks.Add(1)
+			go func(containerDir criContainerDir) {
+				defer containerWorks.Done()
+				s.tailCRIContainer(ctx.Done(), containerDir, buf)
+			}(containerDir)
+		}
+		return nil
+	}
+
+	if err := trackContainers(); err != nil {
+		return err
+	}
+
+	consumeWork := make(chan struct{})
+	go func() {
+		defer close(consumeWork)
+
+		s.consumeLogs(ctx.Done(), buf)
+	}()
+
+	if s.statsHandler != nil {
+		stopReportingStats := s.reportStats()
+		defer stopReportingStats()
+	}
+
+	if s.follow {
+		// in follow mode, pick up new containers until caller cancel
+		ticker := time.NewTicker(s.filePollInterval)
+	pollLoop:
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Log("caller has cancelled the stream")
+				break pollLoop
+			case <-ticker.C:
+				// failures have been logged, retry in next poll
+				_ = trackContainers()
+			}
+		}
+		ticker.Stop()
+	}
+
+	// in non-follow mode, the container workers stop once all logs are read
+	containerWorks.Wait()
+	cancel()
+	s.logger.Log("container workers have stopped")
+	close(buf)
+	<-consumeWork
+	s.logger.Log("consume worker has stopped")
+
+	return nil
+}
+
+// tailCRIContainer reads the current log file of the container. In follow mode, it keeps
+// reading the log file across rotations and restarts, until stopped or the container log
+// directory is removed.
+func (s *Streamer) tailCRIContainer(stop <-chan struct{}, containerDir criContainerDir, buf chan<- LogEntry) {
+	key := containerDir.key
+	stats := s.stats.container(key.Namespace, key.Pod, key.Container)
+	defer s.stats.release(key.Namespace, key.Pod, key.Container)
+	limiter := s.newStreamLimiter()
+
+	s.logger.Log("tailing container log: %s", containerDir.path)
+	defer s.logger.Log("container log tailing has stopped: %s", containerDir.path)
+	s.hooks.PodStreamStarted()
+	defer s.hooks.PodStreamStopped()
+
+	since := s.sinceTime()
+	checkpoint, fromCheckpoint := s.loadCheckpoint(key)
+	seen := func(line criLogLine) bool {
+		if fromCheckpoint {
+			if checkpoint.seen(line.time, line.log) {
+				return true
+			}
+			// identical lines after the resume point are new lines
+			checkpoint = Checkpoint{}
+			return false
+		}
+		return line.time.Before(since)
+	}
+
+	// wait waits for the next poll, it returns false if stopped
+	wait := func() bool {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(s.filePollInterval):
+			return true
+		}
+	}
+
+	var (
+		file   *os.File
+		reader *bufio.Reader
+		// current is the log file being read
+		current criLogFile
+		// offset is the offset of the next line in the file
+		offset int64
+		// pending holds the read content of the line not ended yet, up to maxLogLineBytes
+		pending strings.Builder
+		joiner  criLogJoiner
+		// next is the log file to switch to once the current one is drained
+		next *criLogFile
+	)
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	// open opens the log file to read from the start
+	open := func(logFile criLogFile) error {
+		f, err := os.Open(logFile.path)
+		if err != nil {
+			return err
+		}
+		if file != nil {
+			file.Close()
+		}
+		file = f
+		reader = bufio.NewReader(f)
+		current = logFile
+		offset = 0
+		pending.Reset()
+		return nil
+	}
+
+	// sendJoined sends the full line, it returns false if stopped
+	sendJoined := func(joined criLogLine) bool {
+		if seen(joined) {
+			return true
+		}
+
+		entry := LogEntry{
+			Time:      joined.time,
+			Log:       joined.log,
+			Namespace: key.Namespace,
+			Pod:       key.Pod,
+			Container: key.Container,
+			Stream:    OutputStream(joined.stream),
+		}
+		return s.sendLine(stop, buf, entry, stats, limiter)
+	}
+	// sendLine parses and sends the line read from the current log file, it returns false if stopped
+	sendLine := func(line string) bool {
+		parsed, err := parseCRILogLine(line)
+		if err != nil {
+			s.logger.Log("unable to decode CRI log line in %s: %s", current.path, err)
+			return true
+		}
+		joined, full := joiner.join(parsed)
+		if !full {
+			return true
+		}
+		return sendJoined(joined)
+	}
+	// sendPending sends the line not ended yet, it returns false if stopped
+	sendPending := func() bool {
+		if pending.Len() < 1 {
+			return true
+		}
+		line := pending.String()
+		pending.Reset()
+		return sendLine(line)
+	}
+
+	for {
+		if file == nil {
+			logFile, found, err := currentCRILogFile(containerDir.path)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				return
+			case err != nil:
+				s.logger.Log("failed to list log files of %s: %s", containerDir.path, err)
+				return
+			case !found:
+				if !s.follow || !wait() {
+					return
+				}
+				continue
+			}
+			if err := open(logFile); err != nil {
+				s.logger.Log("failed to open log file %s: %s", logFile.path, err)
+				s.hooks.StreamOpenFailed(streamErrorReason(err))
+				return
+			}
+		}
+
+		chunk, err := reader.ReadSlice('\n')
+		offset += int64(len(chunk))
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+		if room := maxLogLineBytes - pending.Len(); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			pending.Write(chunk)
+		}
+		if err == bufio.ErrBufferFull {
+			// the line is longer than the buffer, keep reading it
+			continue
+		}
+		if err == nil {
+			if !sendPending() {
+				return
+			}
+			continue
+		}
+		if err != io.EOF {
+			s.logger.Log("failed to read log file %s: %s", current.path, err)
+			return
+		}
+
+		// reached the end of the log file
+		if !s.follow {
+			// no more lines are written, send the line not ended and the partial lines
+			if !sendPending() {
+				return
+			}
+			for _, line := range joiner.flush() {
+				if !sendJoined(line) {
+					return
+				}
+			}
+			return
+		}
+		if next != nil {
+			// the old log file has been drained, no more lines are written to it
+			if !sendPending() {
+				return
+			}
+			logFile := *next
+			next = nil
+			if err := open(logFile); err != nil {
+				s.logger.Log("failed to open log file %s: %s", logFile.path, err)
+			}
+			continue
+		}
+		if !wait() {
+			return
+		}
+
+		latest, found, err := currentCRILogFile(containerDir.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// the pod has been removed
+			return
+		case err != nil:
+			s.logger.Log("failed to list log files of %s: %s", containerDir.path, err)
+			continue
+		case found && latest.restart > current.restart:
+			// the container has restarted, read the log file of the new instance after
+			// draining the old one
+			s.logger.Log("container has restarted: %s", latest.path)
+			next = &latest
+			continue
+		}
+
+		fileInfo, err := file.Stat()
+		if err != nil {
+			s.logger.Log("failed to stat log file %s: %s", current.path, err)
+			continue
+		}
+		pathInfo, err := os.Stat(current.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// rotated and the new file is not created yet
+			continue
+		case err != nil:
+			s.logger.Log("failed to stat log file %s: %s", current.path, err)
+			continue
+		case !os.SameFile(fileInfo, pathInfo):
+			// the log file has been rotated, read the new one after draining the old one,
+			// which may still have lines written before the runtime reopens the log file
+			s.logger.Log("log file has been rotated: %s", current.path)
+			rotated := current
+			next = &rotated
+		case pathInfo.Size() < offset:
+			// the log file has been truncated in place
+			s.logger.Log("log file has been truncated: %s", current.path)
+			if err := open(current); err != nil {
+				s.logger.Log("failed to open log file %s: %s", current.path, err)
+			}
+		}
+	}
+}
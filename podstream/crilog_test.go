@@ -0,0 +1,269 @@
+package podstream
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCRILogLine(t *testing.T) {
+	cases := []struct {
+		line     string
+		expected criLogLine
+		err      bool
+	}{
+		{
+			line: "2022-01-01T00:00:00.123456789Z stdout F hello world",
+			expected: criLogLine{
+				time:   time.Date(2022, 1, 1, 0, 0, 0, 123456789, time.UTC),
+				stream: "stdout",
+				log:    "hello world",
+			},
+		},
+		{
+			line: "2022-01-01T00:00:00Z stderr P partial ",
+			expected: criLogLine{
+				time:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				stream:  "stderr",
+				partial: true,
+				log:     "partial ",
+			},
+		},
+		{
+			line: "2022-01-01T00:00:00Z stdout F",
+			expected: criLogLine{
+				time:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				stream: "stdout",
+			},
+		},
+		{line: "2022-01-01T00:00:00Z stdout", err: true},
+		{line: "yesterday stdout F hello", err: true},
+		{line: "2022-01-01T00:00:00Z stdout X hello", err: true},
+	}
+
+	for _, c := range cases {
+		parsed, err := parseCRILogLine(c.line)
+		if c.err {
+			assert.Error(t, err, c.line)
+			continue
+		}
+		if assert.NoError(t, err, c.line) {
+			assert.Equal(t, c.expected, parsed, c.line)
+		}
+	}
+}
+
+func TestCRILogJoiner(t *testing.T) {
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var joiner criLogJoiner
+
+	_, full := joiner.join(criLogLine{time: at, stream: "stdout", partial: true, log: "hello "})
+	assert.False(t, full)
+	line, full := joiner.join(criLogLine{time: at.Add(time.Second), stream: "stderr", log: "error"})
+	assert.True(t, full)
+	assert.Equal(t, "error", line.log)
+	_, full = joiner.join(criLogLine{time: at.Add(time.Second), stream: "stdout", partial: true, log: "big "})
+	assert.False(t, full)
+	line, full = joiner.join(criLogLine{time: at.Add(2 * time.Second), stream: "stdout", log: "world"})
+	assert.True(t, full)
+	assert.Equal(t, criLogLine{time: at, stream: "stdout", log: "hello big world"}, line)
+}
+
+func writeCRILog(t *testing.T, path string, lines ...string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	for _, line := range lines {
+		_, err := f.WriteString(line + "\n")
+		assert.NoError(t, err)
+	}
+}
+
+func TestStreamCRILogs(t *testing.T) {
+	logDir := t.TempDir()
+	writeCRILog(
+		t, filepath.Join(logDir, "ns_pod-a_uid-a", "app", "0.log"),
+		"2022-01-01T00:00:02Z stdout F second",
+		"2022-01-01T00:00:03Z stdout P third ",
+		"2022-01-01T00:00:03Z stdout F line",
+	)
+	writeCRILog(
+		t, filepath.Join(logDir, "ns_pod-a_uid-a", "app", "0.log.20220101-000001"),
+		"2022-01-01T00:00:01Z stdout F rotated",
+	)
+	writeCRILog(
+		t, filepath.Join(logDir, "ns_pod-a_uid-a", "sidecar", "0.log"),
+		"2022-01-01T00:00:01Z stdout F sidecar",
+	)
+	writeCRILog(
+		t, filepath.Join(logDir, "ns_pod-b_uid-b", "app", "0.log"),
+		"2022-01-01T00:00:00Z stdout F old instance",
+	)
+	writeCRILog(
+		t, filepath.Join(logDir, "ns_pod-b_uid-b", "app", "1.log"),
+		"2022-01-01T00:00:01Z stderr F first",
+		"not a CRI log line",
+	)
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "not-a-pod"), nil, 0644))
+
+	collected := &collectedLogs{}
+	err := StreamCRILogs(
+		make(chan struct{}),
+		logDir,
+		FromContainer("app"),
+		ConsumeLogsWith(collected),
+	)
+	assert.NoError(t, err)
+
+	var logs []string
+	for _, log := range collected.logs {
+		assert.Equal(t, "ns", log.Namespace)
+		assert.Equal(t, "app", log.Container)
+		logs = append(logs, log.Pod+": "+log.Log)
+	}
+	assert.Equal(t, []string{"pod-b: first", "pod-a: second", "pod-a: third line"}, logs)
+	if assert.Len(t, collected.logs, 3) {
+		assert.Equal(t, StreamStderr, collected.logs[0].Stream)
+		assert.Equal(t, StreamStdout, collected.logs[1].Stream)
+	}
+
+	collected = &collectedLogs{}
+	err = StreamCRILogs(
+		make(chan struct{}),
+		logDir,
+		FromOutputStreams(StreamStderr),
+		ConsumeLogsWith(collected),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"pod-b": 1}, collected.pods())
+	assert.Error(t, StreamCRILogs(make(chan struct{}), logDir, FromOutputStreams("stdin")))
+
+	collected = &collectedLogs{}
+	err = StreamCRILogs(
+		make(chan struct{}),
+		logDir,
+		SinceTime(time.Date(2022, 1, 1, 0, 0, 2, 0, time.UTC)),
+		ConsumeLogsWith(collected),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"pod-a": 2}, collected.pods())
+
+	err = StreamCRILogs(make(chan struct{}), filepath.Join(logDir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestStreamCRILogs_Unterminated(t *testing.T) {
+	logDir := t.TempDir()
+	logFile := filepath.Join(logDir, "ns_pod-a_uid-a", "app", "0.log")
+	long := strings.Repeat("a", 2*maxLogLineBytes)
+	writeCRILog(
+		t, logFile,
+		"2022-01-01T00:00:00Z stdout F "+long,
+		"2022-01-01T00:00:01Z stderr P partial",
+	)
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.WriteString("2022-01-01T00:00:02Z stdout F unterminated")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	collected := &collectedLogs{}
+	err = StreamCRILogs(make(chan struct{}), logDir, ConsumeLogsWith(collected))
+	assert.NoError(t, err)
+
+	logs := map[string]string{}
+	for _, log := range collected.logs {
+		if strings.HasPrefix(log.Log, "aaa") {
+			assert.Less(t, len(log.Log), maxLogLineBytes, "long lines should be truncated")
+			logs[string(log.Stream)] = "long"
+			continue
+		}
+		logs[string(log.Stream)+" "+log.Log] = log.Log
+	}
+	assert.Equal(t, map[string]string{
+		"stdout":              "long",
+		"stderr partial":      "partial",
+		"stdout unterminated": "unterminated",
+	}, logs, "unterminated and partial lines should be sent at the end")
+}
+
+func TestStreamCRILogs_Follow(t *testing.T) {
+	logDir := t.TempDir()
+	logFile := filepath.Join(logDir, "ns_pod-a_uid-a", "app", "0.log")
+	writeCRILog(t, logFile, "2022-01-01T00:00:00Z stdout F first")
+
+	collected := &collectedLogs{}
+	streamer, err := NewStreamer(
+		nil,
+		FollowSelectedPods(""),
+		ConsumeLogsWith(collected),
+	)
+	assert.NoError(t, err)
+	streamer.emitLogsInterval = 10 * time.Millisecond
+	streamer.filePollInterval = 10 * time.Millisecond
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := streamer.streamCRILogs(stop, logDir)
+		assert.NoError(t, err)
+	}()
+
+	logsOf := func() string {
+		collected.mu.Lock()
+		defer collected.mu.Unlock()
+
+		var logs []string
+		for _, log := range collected.logs {
+			logs = append(logs, log.Pod+": "+log.Log)
+		}
+		return strings.Join(logs, ", ")
+	}
+	waitFor := func(expected string) {
+		assert.Eventually(t, func() bool {
+			return logsOf() == expected
+		}, 5*time.Second, 10*time.Millisecond, "expected logs: %s, got: %s", expected, logsOf())
+	}
+
+	waitFor("pod-a: first")
+
+	// appended
+	writeCRILog(t, logFile, "2022-01-01T00:00:01Z stdout F second")
+	waitFor("pod-a: first, pod-a: second")
+
+	// rotated, lines are written to the old file until the runtime reopens the log file
+	rotatedFile := logFile + ".20220101-000001"
+	assert.NoError(t, os.Rename(logFile, rotatedFile))
+	writeCRILog(t, rotatedFile, "2022-01-01T00:00:01Z stdout F after rename")
+	f, err := os.OpenFile(rotatedFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if assert.NoError(t, err) {
+		// the last line is not ended
+		_, err = f.WriteString("2022-01-01T00:00:01Z stdout F unended")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+	}
+	writeCRILog(t, logFile, "2022-01-01T00:00:02Z stdout F rotated")
+	waitFor("pod-a: first, pod-a: second, pod-a: after rename, pod-a: unended, pod-a: rotated")
+
+	// restarted
+	writeCRILog(t, filepath.Join(filepath.Dir(logFile), "1.log"), "2022-01-01T00:00:03Z stdout F restarted")
+	waitFor("pod-a: first, pod-a: second, pod-a: after rename, pod-a: unended, pod-a: rotated, pod-a: restarted")
+
+	// new pod
+	writeCRILog(t, filepath.Join(logDir, "ns_pod-b_uid-b", "app", "0.log"), "2022-01-01T00:00:04Z stdout F new pod")
+	waitFor("pod-a: first, pod-a: second, pod-a: after rename, pod-a: unended, pod-a: rotated, pod-a: restarted, pod-b: new pod")
+
+	close(stop)
+	wg.Wait()
+}
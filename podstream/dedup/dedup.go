@@ -0,0 +1,150 @@
+package dedup
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// run tracks the consecutive repeats of a line from a container.
+type run struct {
+	// start is the time of the first line of the run.
+	start  time.Time
+	tokens []string
+
+	// collapsed is the entry collapsing the suppressed repeats.
+	collapsed podstream.LogEntry
+}
+
+// Deduplicator is a podstream.LogEntryTransformer collapsing identical or near identical
+// consecutive lines per container.
+//
+// The first line of a run is passed through as is. Its repeats within the window are
+// suppressed, and emitted as one entry with Repeats and LastTime set once the run ends,
+// the window elapses or the stream stops.
+type Deduplicator struct {
+	window              time.Duration
+	similarityThreshold float64
+
+	mu     sync.Mutex
+	runs   map[string]*run
+	latest time.Time
+}
+
+var (
+	_ podstream.LogEntryTransformer = (*Deduplicator)(nil)
+	_ podstream.LogEntryFlusher     = (*Deduplicator)(nil)
+)
+
+// New creates a deduplicator.
+func New(options ...Option) (*Deduplicator, error) {
+	deduplicator := &Deduplicator{
+		window:              1 * time.Minute,
+		similarityThreshold: 1,
+		runs:                map[string]*run{},
+	}
+	for _, opt := range options {
+		if err := opt(deduplicator); err != nil {
+			return nil, err
+		}
+	}
+
+	return deduplicator, nil
+}
+
+func containerKey(entry podstream.LogEntry) string {
+	return entry.Namespace + "/" + entry.Pod + "/" + entry.Container
+}
+
+// similar tells if the tokens of two lines are similar enough to be repeats.
+func (d *Deduplicator) similar(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) < 1 {
+		return true
+	}
+
+	equal := 0
+	for idx := range a {
+		if a[idx] == b[idx] {
+			equal++
+		}
+	}
+	return float64(equal)/float64(len(a)) >= d.similarityThreshold
+}
+
+// TransformLogs collapses the repeats in the logs.
+func (d *Deduplicator) TransformLogs(logs []podstream.LogEntry) []podstream.LogEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rv := make([]podstream.LogEntry, 0, len(logs))
+	for _, entry := range logs {
+		if entry.Time.After(d.latest) {
+			d.latest = entry.Time
+		}
+
+		key := containerKey(entry)
+		tokens := strings.Fields(entry.Log)
+		if r, exists := d.runs[key]; exists {
+			if entry.Time.Sub(r.start) < d.window && d.similar(r.tokens, tokens) {
+				if r.collapsed.Repeats == 0 {
+					r.collapsed = entry
+				}
+				r.collapsed.Repeats++
+				lastTime := entry.Time
+				r.collapsed.LastTime = &lastTime
+				continue
+			}
+
+			// the run has ended
+			if r.collapsed.Repeats > 0 {
+				rv = append(rv, r.collapsed)
+			}
+		}
+
+		d.runs[key] = &run{start: entry.Time, tokens: tokens}
+		rv = append(rv, entry)
+	}
+
+	// emit the runs that can no longer collapse new lines
+	var expired []podstream.LogEntry
+	for key, r := range d.runs {
+		if d.latest.Sub(r.start) < d.window {
+			continue
+		}
+		if r.collapsed.Repeats > 0 {
+			expired = append(expired, r.collapsed)
+		}
+		delete(d.runs, key)
+	}
+
+	return append(rv, sortByTime(expired)...)
+}
+
+func sortByTime(logs []podstream.LogEntry) []podstream.LogEntry {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Time.Before(logs[j].Time)
+	})
+	return logs
+}
+
+// FlushLogs emits the collapsed entries of all pending runs.
+func (d *Deduplicator) FlushLogs() []podstream.LogEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var rv []podstream.LogEntry
+	for key, r := range d.runs {
+		if r.collapsed.Repeats > 0 {
+			rv = append(rv, r.collapsed)
+		}
+		delete(d.runs, key)
+	}
+
+	return sortByTime(rv)
+}
@@ -0,0 +1,87 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d, err := New(WithWindow(10 * time.Second))
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	lastTime := func(seconds int) *time.Time {
+		t := at(seconds)
+		return &t
+	}
+
+	logs := d.TransformLogs([]podstream.LogEntry{
+		{Time: at(0), Pod: "a", Log: "connection refused"},
+		{Time: at(1), Pod: "a", Log: "connection refused"},
+		{Time: at(1), Pod: "b", Log: "connection refused"},
+		{Time: at(2), Pod: "a", Log: "connection refused"},
+		{Time: at(3), Pod: "a", Log: "retrying"},
+	})
+	if assert.Len(t, logs, 4) {
+		assert.Equal(t, "a", logs[0].Pod)
+		assert.Zero(t, logs[0].Repeats)
+		assert.Nil(t, logs[0].LastTime)
+		assert.Equal(t, "b", logs[1].Pod, "repeats are tracked per container")
+
+		assert.Equal(t, "connection refused", logs[2].Log)
+		assert.Equal(t, 2, logs[2].Repeats)
+		assert.Equal(t, at(1), logs[2].Time)
+		assert.Equal(t, lastTime(2), logs[2].LastTime)
+
+		assert.Equal(t, "retrying", logs[3].Log)
+	}
+
+	// repeats across calls, until the window elapses
+	var repeated []podstream.LogEntry
+	for i := 4; i < 20; i++ {
+		repeated = append(repeated, podstream.LogEntry{Time: at(i), Pod: "a", Log: "retrying"})
+	}
+	logs = d.TransformLogs(repeated)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, 9, logs[0].Repeats)
+		assert.Equal(t, lastTime(12), logs[0].LastTime)
+		assert.Zero(t, logs[1].Repeats, "new run after window elapses")
+		assert.Equal(t, at(13), logs[1].Time)
+	}
+
+	logs = d.FlushLogs()
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, 6, logs[0].Repeats)
+		assert.Equal(t, lastTime(19), logs[0].LastTime)
+	}
+	assert.Empty(t, d.FlushLogs())
+}
+
+func TestDeduplicator_Similarity(t *testing.T) {
+	d, err := New(WithSimilarityThreshold(0.75))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	var logs []podstream.LogEntry
+	for i := 0; i < 5; i++ {
+		logs = append(logs, podstream.LogEntry{Time: now, Log: fmt.Sprintf("GET /healthz 200 %dms", i)})
+	}
+	logs = append(logs, podstream.LogEntry{Time: now, Log: "POST /api 500 3ms"})
+
+	logs = d.TransformLogs(logs)
+	if assert.Len(t, logs, 3) {
+		assert.Equal(t, "GET /healthz 200 0ms", logs[0].Log)
+		assert.Equal(t, 4, logs[1].Repeats)
+		assert.Equal(t, "POST /api 500 3ms", logs[2].Log)
+	}
+
+	_, err = New(WithSimilarityThreshold(0))
+	assert.Error(t, err)
+}
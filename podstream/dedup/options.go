@@ -0,0 +1,31 @@
+package dedup
+
+import (
+	"errors"
+	"time"
+)
+
+// WithWindow sets the max time range of repeats collapsed into one entry. Defaults to 1 minute.
+func WithWindow(window time.Duration) Option {
+	return func(deduplicator *Deduplicator) error {
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+
+		deduplicator.window = window
+		return nil
+	}
+}
+
+// WithSimilarityThreshold sets the min ratio of equal tokens for a line to be a repeat of
+// the previous line. Defaults to 1, which only collapses identical lines.
+func WithSimilarityThreshold(threshold float64) Option {
+	return func(deduplicator *Deduplicator) error {
+		if threshold <= 0 || threshold > 1 {
+			return errors.New("similarity threshold must be in (0, 1]")
+		}
+
+		deduplicator.similarityThreshold = threshold
+		return nil
+	}
+}
@@ -0,0 +1,4 @@
+package dedup
+
+// Option specifies options for configuring the deduplicator.
+type Option func(deduplicator *Deduplicator) error
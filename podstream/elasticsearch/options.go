@@ -0,0 +1,85 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the sink.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(sink *Sink) error {
+		sink.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client to send bulk requests with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(sink *Sink) error {
+		sink.httpClient = client
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each bulk request sent with the default http client.
+// Defaults to 10 seconds. Clients set by WithHTTPClient use their own timeouts.
+func WithTimeout(timeout time.Duration) Option {
+	return func(sink *Sink) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		sink.timeout = timeout
+		return nil
+	}
+}
+
+// WithBasicAuth sets the basic auth credentials for the bulk requests.
+func WithBasicAuth(username, password string) Option {
+	return func(sink *Sink) error {
+		sink.username = username
+		sink.password = password
+		return nil
+	}
+}
+
+// WithIndexTemplate sets the index name template.
+// The template is rendered with the log entry, for example:
+//
+//	podstream-{{ .Namespace }}-{{ .Time.Format "2006.01.02" }}
+func WithIndexTemplate(text string) Option {
+	return func(sink *Sink) error {
+		tmpl, err := template.New("index").Parse(text)
+		if err != nil {
+			return err
+		}
+
+		sink.indexTemplate = tmpl
+		return nil
+	}
+}
+
+// WithRetry sets the max retry attempts and the backoff between attempts.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(sink *Sink) error {
+		if maxRetries < 0 {
+			return errors.New("max retries must not be negative")
+		}
+
+		sink.maxRetries = maxRetries
+		sink.retryBackoff = backoff
+		return nil
+	}
+}
+
+// OnRejected sets the handler to be called with documents that cannot be indexed.
+func OnRejected(handler RejectedDocumentsHandler) Option {
+	return func(sink *Sink) error {
+		sink.onRejected = handler
+		return nil
+	}
+}
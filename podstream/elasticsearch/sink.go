@@ -0,0 +1,315 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+const defaultIndexTemplate = `podstream-{{ .Time.Format "2006.01.02" }}`
+
+// Sink is a podstream.LogEntryConsumer that indexes log entries into
+// Elasticsearch / OpenSearch with the _bulk API.
+//
+// Documents are created with IDs derived from the log entry content, so
+// retrying a batch won't index the same entry twice.
+type Sink struct {
+	logger logger.Logger
+
+	// httpClient is the client used to send bulk requests.
+	httpClient *http.Client
+
+	// timeout specifies the timeout of the default http client.
+	timeout time.Duration
+
+	// bulkURL is the url of the _bulk endpoint.
+	bulkURL string
+
+	// username and password specify the basic auth credentials.
+	username string
+	password string
+
+	// indexTemplate renders the index name for a log entry.
+	indexTemplate *template.Template
+
+	// maxRetries specifies the max retry attempts for retryable failures.
+	maxRetries int
+
+	// retryBackoff specifies the base backoff between retry attempts.
+	retryBackoff time.Duration
+
+	// onRejected is called with the documents that cannot be indexed.
+	onRejected RejectedDocumentsHandler
+}
+
+var _ podstream.LogEntryConsumer = (*Sink)(nil)
+
+// NewSink creates a bulk sink sending to the given Elasticsearch / OpenSearch endpoint.
+func NewSink(endpoint string, options ...Option) (*Sink, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	sink := &Sink{
+		logger:       logger.NoOp,
+		timeout:      10 * time.Second,
+		bulkURL:      strings.TrimSuffix(endpoint, "/") + "/_bulk",
+		maxRetries:   3,
+		retryBackoff: 500 * time.Millisecond,
+	}
+	for _, opt := range options {
+		if err := opt(sink); err != nil {
+			return nil, err
+		}
+	}
+	if sink.logger == nil {
+		sink.logger = logger.NoOp
+	}
+	if sink.httpClient == nil {
+		sink.httpClient = &http.Client{Timeout: sink.timeout}
+	}
+	if sink.indexTemplate == nil {
+		sink.indexTemplate = template.Must(template.New("index").Parse(defaultIndexTemplate))
+	}
+
+	return sink, nil
+}
+
+// bulkDocument is a document pending to be indexed.
+type bulkDocument struct {
+	index  string
+	id     string
+	source []byte
+
+	// status and reason record the last failure of the document.
+	status int
+	reason string
+}
+
+func (d *bulkDocument) rejected() RejectedDocument {
+	return RejectedDocument{
+		Index:    d.index,
+		ID:       d.id,
+		Status:   d.status,
+		Reason:   d.reason,
+		Document: d.source,
+	}
+}
+
+// document is the indexed form of a log entry.
+type document struct {
+	Timestamp time.Time `json:"@timestamp"`
+	podstream.LogEntry
+}
+
+// DocumentID returns the document ID for the log entry.
+func DocumentID(entry podstream.LogEntry) string {
+	h := sha256.New()
+	if entry.Cluster != "" {
+		// only hashed when set, so the IDs of entries without cluster are kept
+		io.WriteString(h, entry.Cluster)
+		h.Write([]byte{0})
+	}
+	for _, v := range []string{
+		entry.Namespace,
+		entry.Pod,
+		entry.Container,
+		entry.Time.UTC().Format(time.RFC3339Nano),
+		entry.Log,
+	} {
+		io.WriteString(h, v)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Sink) OnLogs(logs []podstream.LogEntry) {
+	var (
+		pending  []*bulkDocument
+		rejected []RejectedDocument
+	)
+	for _, entry := range logs {
+		doc, err := s.newBulkDocument(entry)
+		if err != nil {
+			rejected = append(rejected, doc.rejected())
+			continue
+		}
+		pending = append(pending, doc)
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.retryBackoff << (attempt - 1))
+		}
+
+		var failed []RejectedDocument
+		pending, failed = s.send(pending)
+		rejected = append(rejected, failed...)
+
+		if len(pending) > 0 && attempt >= s.maxRetries {
+			s.logger.Log("giving up %d documents after %d retries", len(pending), attempt)
+			for _, doc := range pending {
+				rejected = append(rejected, doc.rejected())
+			}
+			break
+		}
+	}
+
+	if len(rejected) > 0 {
+		s.logger.Log("%d documents rejected", len(rejected))
+		if s.onRejected != nil {
+			s.onRejected(rejected)
+		}
+	}
+}
+
+func (s *Sink) newBulkDocument(entry podstream.LogEntry) (*bulkDocument, error) {
+	doc := &bulkDocument{id: DocumentID(entry)}
+
+	var index strings.Builder
+	if err := s.indexTemplate.Execute(&index, entry); err != nil {
+		doc.reason = fmt.Sprintf("render index name: %s", err)
+		return doc, err
+	}
+	doc.index = index.String()
+
+	source, err := json.Marshal(document{Timestamp: entry.Time, LogEntry: entry})
+	if err != nil {
+		doc.reason = fmt.Sprintf("encode document: %s", err)
+		return doc, err
+	}
+	doc.source = source
+
+	return doc, nil
+}
+
+type bulkAction struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type bulkResponse struct {
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+// isRetryableStatus tells if a request or document failed with the status should be retried.
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// send sends the documents in a single bulk request. It returns the documents to retry
+// and the documents rejected.
+func (s *Sink) send(docs []*bulkDocument) ([]*bulkDocument, []RejectedDocument) {
+	failAll := func(status int, reason string) ([]*bulkDocument, []RejectedDocument) {
+		for _, doc := range docs {
+			doc.status = status
+			doc.reason = reason
+		}
+		if status == 0 || isRetryableStatus(status) {
+			return docs, nil
+		}
+
+		var rejected []RejectedDocument
+		for _, doc := range docs {
+			rejected = append(rejected, doc.rejected())
+		}
+		return nil, rejected
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		// "create" makes retried documents conflict instead of being indexed again
+		if err := encoder.Encode(map[string]bulkAction{
+			"create": {Index: doc.index, ID: doc.id},
+		}); err != nil {
+			return failAll(0, fmt.Sprintf("encode bulk action: %s", err))
+		}
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.bulkURL, &body)
+	if err != nil {
+		return failAll(0, fmt.Sprintf("create bulk request: %s", err))
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.username != "" || s.password != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Log("bulk request failed: %s", err)
+		return failAll(0, fmt.Sprintf("bulk request: %s", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return failAll(0, fmt.Sprintf("read bulk response: %s", err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		s.logger.Log("bulk request failed with status %d", resp.StatusCode)
+		return failAll(resp.StatusCode, fmt.Sprintf("bulk request: %s", strings.TrimSpace(string(respBody))))
+	}
+
+	var bulkResp bulkResponse
+	if err := json.Unmarshal(respBody, &bulkResp); err != nil {
+		return failAll(0, fmt.Sprintf("decode bulk response: %s", err))
+	}
+	if !bulkResp.Errors {
+		return nil, nil
+	}
+	if len(bulkResp.Items) != len(docs) {
+		return failAll(0, fmt.Sprintf("bulk response has %d items, expected %d", len(bulkResp.Items), len(docs)))
+	}
+
+	var (
+		retry    []*bulkDocument
+		rejected []RejectedDocument
+	)
+	for idx, item := range bulkResp.Items {
+		doc := docs[idx]
+		for _, result := range item {
+			switch {
+			case result.Status >= 200 && result.Status <= 299:
+			case result.Status == http.StatusConflict:
+				// the document has been indexed by a previous attempt
+			default:
+				doc.status = result.Status
+				doc.reason = http.StatusText(result.Status)
+				if result.Error != nil {
+					doc.reason = fmt.Sprintf("%s: %s", result.Error.Type, result.Error.Reason)
+				}
+				if isRetryableStatus(result.Status) {
+					retry = append(retry, doc)
+				} else {
+					rejected = append(rejected, doc.rejected())
+				}
+			}
+		}
+	}
+
+	return retry, rejected
+}
@@ -0,0 +1,199 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkRequestItem struct {
+	action bulkAction
+	doc    map[string]interface{}
+}
+
+// fakeBulkServer is a local stand-in of the _bulk endpoint.
+type fakeBulkServer struct {
+	mu       sync.Mutex
+	requests [][]bulkRequestItem
+
+	// respond decides the status of each item by attempt.
+	respond func(attempt int, item bulkRequestItem) int
+}
+
+func (s *fakeBulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var items []bulkRequestItem
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]bulkAction
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !scanner.Scan() {
+			http.Error(w, "missing document", http.StatusBadRequest)
+			return
+		}
+		item := bulkRequestItem{action: action["create"]}
+		if err := json.Unmarshal(scanner.Bytes(), &item.doc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items = append(items, item)
+	}
+
+	s.mu.Lock()
+	attempt := len(s.requests)
+	s.requests = append(s.requests, items)
+	s.mu.Unlock()
+
+	resp := bulkResponse{}
+	for _, item := range items {
+		status := s.respond(attempt, item)
+		result := bulkItemResult{Status: status}
+		if status > 299 {
+			resp.Errors = true
+			result.Error = &struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			}{Type: "test_exception", Reason: fmt.Sprintf("status %d", status)}
+		}
+		resp.Items = append(resp.Items, map[string]bulkItemResult{"create": result})
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestSink(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	logs := []podstream.LogEntry{
+		{Time: now, Log: "ok", Namespace: "ns", Pod: "pod-1"},
+		{Time: now, Log: "throttled", Namespace: "ns", Pod: "pod-1"},
+		{Time: now, Log: "malformed", Namespace: "ns", Pod: "pod-2"},
+	}
+
+	server := &fakeBulkServer{
+		respond: func(attempt int, item bulkRequestItem) int {
+			switch item.doc["log"] {
+			case "throttled":
+				if attempt == 0 {
+					return http.StatusTooManyRequests
+				}
+				return http.StatusCreated
+			case "malformed":
+				return http.StatusBadRequest
+			default:
+				return http.StatusCreated
+			}
+		},
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	var rejected []RejectedDocument
+	sink, err := NewSink(
+		httpServer.URL,
+		WithIndexTemplate(`logs-{{ .Namespace }}-{{ .Time.Format "2006.01.02" }}`),
+		WithRetry(2, time.Millisecond),
+		OnRejected(func(docs []RejectedDocument) {
+			rejected = append(rejected, docs...)
+		}),
+	)
+	assert.NoError(t, err)
+
+	sink.OnLogs(logs)
+
+	if assert.Len(t, server.requests, 2) {
+		assert.Len(t, server.requests[0], 3)
+		for _, item := range server.requests[0] {
+			assert.Equal(t, "logs-ns-2022.05.01", item.action.Index)
+		}
+		if assert.Len(t, server.requests[1], 1, "only the throttled document should be retried") {
+			assert.Equal(t, "throttled", server.requests[1][0].doc["log"])
+			assert.Equal(t, server.requests[0][1].action.ID, server.requests[1][0].action.ID)
+		}
+	}
+
+	if assert.Len(t, rejected, 1) {
+		assert.Equal(t, http.StatusBadRequest, rejected[0].Status)
+		assert.Equal(t, DocumentID(logs[2]), rejected[0].ID)
+		assert.True(t, strings.Contains(rejected[0].Reason, "test_exception"))
+	}
+}
+
+func TestSink_GiveUp(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer httpServer.Close()
+
+	var rejected []RejectedDocument
+	sink, err := NewSink(
+		httpServer.URL,
+		WithRetry(1, time.Millisecond),
+		OnRejected(func(docs []RejectedDocument) {
+			rejected = append(rejected, docs...)
+		}),
+	)
+	assert.NoError(t, err)
+
+	sink.OnLogs([]podstream.LogEntry{{Time: time.Now(), Log: "test"}})
+
+	if assert.Len(t, rejected, 1) {
+		assert.Equal(t, http.StatusServiceUnavailable, rejected[0].Status)
+	}
+}
+
+func TestSink_Timeout(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer httpServer.Close()
+
+	var rejected []RejectedDocument
+	sink, err := NewSink(
+		httpServer.URL,
+		WithTimeout(20*time.Millisecond),
+		WithRetry(0, time.Millisecond),
+		OnRejected(func(docs []RejectedDocument) {
+			rejected = append(rejected, docs...)
+		}),
+	)
+	assert.NoError(t, err)
+
+	sink.OnLogs([]podstream.LogEntry{{Time: time.Now(), Log: "test"}})
+	if assert.Len(t, rejected, 1) {
+		assert.Contains(t, rejected[0].Reason, "bulk request")
+	}
+
+	_, err = NewSink(httpServer.URL, WithTimeout(0))
+	assert.Error(t, err)
+}
+
+func TestDocumentID(t *testing.T) {
+	now := time.Now()
+	a := podstream.LogEntry{Time: now, Log: "test", Pod: "pod-1"}
+	b := podstream.LogEntry{Time: now, Log: "test", Pod: "pod-2"}
+
+	assert.Equal(t, DocumentID(a), DocumentID(a))
+	assert.NotEqual(t, DocumentID(a), DocumentID(b))
+
+	c := a
+	c.Cluster = "cluster-1"
+	d := a
+	d.Cluster = "cluster-2"
+	assert.NotEqual(t, DocumentID(a), DocumentID(c))
+	assert.NotEqual(t, DocumentID(c), DocumentID(d))
+}
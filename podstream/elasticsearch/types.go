@@ -0,0 +1,22 @@
+package elasticsearch
+
+// RejectedDocument describes a log entry document rejected by the bulk API.
+type RejectedDocument struct {
+	// Index is the index the document was sent to.
+	Index string `json:"index"`
+	// ID is the document ID.
+	ID string `json:"id"`
+	// Status is the HTTP status reported for the document.
+	// It is 0 if the whole bulk request failed.
+	Status int `json:"status"`
+	// Reason describes why the document was rejected.
+	Reason string `json:"reason"`
+	// Document is the rejected document source.
+	Document []byte `json:"document"`
+}
+
+// RejectedDocumentsHandler handles rejected documents.
+type RejectedDocumentsHandler func(docs []RejectedDocument)
+
+// Option specifies options for configuring the bulk sink.
+type Option func(sink *Sink) error
@@ -0,0 +1,417 @@
+package podstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedeventsv1 "k8s.io/client-go/kubernetes/typed/events/v1"
+)
+
+// The backoff between watching the events again, doubled on each failure until an
+// event is received.
+const (
+	minRewatchBackoff = 1 * time.Second
+	maxRewatchBackoff = 30 * time.Second
+)
+
+// eventSource lists and watches Kubernetes Events.
+type eventSource interface {
+	// list returns the events and the resource version to watch from.
+	list(ctx context.Context) ([]runtime.Object, string, error)
+	watch(ctx context.Context, resourceVersion string) (watch.Interface, error)
+	// convert converts the event to a log entry and the UID of the object it is about.
+	convert(obj runtime.Object) (types.UID, LogEntry, bool)
+}
+
+// eventObject describes the object an event is about.
+type eventObject struct {
+	uid       types.UID
+	kind      string
+	name      string
+	fieldPath string
+}
+
+// eventEntry formats the event as a log entry.
+func eventEntry(
+	namespace string,
+	object eventObject,
+	eventType string,
+	reason string,
+	message string,
+	at time.Time,
+) LogEntry {
+	entry := LogEntry{
+		Kind:      EntryKindEvent,
+		Time:      at,
+		Namespace: namespace,
+		Log: fmt.Sprintf(
+			"%s %s %s/%s: %s",
+			eventType, reason, strings.ToLower(object.kind), object.name, message,
+		),
+	}
+	if object.kind == "Pod" {
+		entry.Pod = object.name
+		// e.g. spec.containers{app}
+		if strings.HasPrefix(object.fieldPath, "spec.containers{") && strings.HasSuffix(object.fieldPath, "}") {
+			entry.Container = strings.TrimSuffix(strings.TrimPrefix(object.fieldPath, "spec.containers{"), "}")
+		}
+	}
+	return entry
+}
+
+// firstTime returns the first non zero time.
+func firstTime(times ...time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// coreEventSource reads core/v1 Events.
+type coreEventSource struct {
+	client typedcorev1.EventInterface
+}
+
+func (s coreEventSource) list(ctx context.Context) ([]runtime.Object, string, error) {
+	events, err := s.client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	objs := make([]runtime.Object, 0, len(events.Items))
+	for idx := range events.Items {
+		objs = append(objs, &events.Items[idx])
+	}
+	return objs, events.ResourceVersion, nil
+}
+
+func (s coreEventSource) watch(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+	return s.client.Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+}
+
+func (s coreEventSource) convert(obj runtime.Object) (types.UID, LogEntry, bool) {
+	event, ok := obj.(*corev1.Event)
+	if !ok {
+		return "", LogEntry{}, false
+	}
+
+	object := eventObject{
+		uid:       event.InvolvedObject.UID,
+		kind:      event.InvolvedObject.Kind,
+		name:      event.InvolvedObject.Name,
+		fieldPath: event.InvolvedObject.FieldPath,
+	}
+	at := firstTime(
+		event.LastTimestamp.Time,
+		event.EventTime.Time,
+		event.FirstTimestamp.Time,
+		event.CreationTimestamp.Time,
+	)
+	return object.uid, eventEntry(event.Namespace, object, event.Type, event.Reason, event.Message, at), true
+}
+
+// eventsV1Source reads events.k8s.io/v1 Events.
+type eventsV1Source struct {
+	client typedeventsv1.EventInterface
+}
+
+func (s eventsV1Source) list(ctx context.Context) ([]runtime.Object, string, error) {
+	events, err := s.client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	objs := make([]runtime.Object, 0, len(events.Items))
+	for idx := range events.Items {
+		objs = append(objs, &events.Items[idx])
+	}
+	return objs, events.ResourceVersion, nil
+}
+
+func (s eventsV1Source) watch(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+	return s.client.Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+}
+
+func (s eventsV1Source) convert(obj runtime.Object) (types.UID, LogEntry, bool) {
+	event, ok := obj.(*eventsv1.Event)
+	if !ok {
+		return "", LogEntry{}, false
+	}
+
+	object := eventObject{
+		uid:       event.Regarding.UID,
+		kind:      event.Regarding.Kind,
+		name:      event.Regarding.Name,
+		fieldPath: event.Regarding.FieldPath,
+	}
+	var lastObserved time.Time
+	if event.Series != nil {
+		lastObserved = event.Series.LastObservedTime.Time
+	}
+	at := firstTime(
+		lastObserved,
+		event.EventTime.Time,
+		event.DeprecatedLastTimestamp.Time,
+		event.CreationTimestamp.Time,
+	)
+	return object.uid, eventEntry(event.Namespace, object, event.Type, event.Reason, event.Note, at), true
+}
+
+// sinceTime returns the time to stream logs after. It is zero if not limited.
+func (s *Streamer) sinceTime() time.Time {
+	switch {
+	case s.podLogOptions.SinceTime != nil:
+		return s.podLogOptions.SinceTime.Time
+	case s.podLogOptions.SinceSeconds != nil:
+		return time.Now().Add(-time.Duration(*s.podLogOptions.SinceSeconds) * time.Second)
+	default:
+		return time.Time{}
+	}
+}
+
+// eventTargets tracks the selected pods and their owners to emit events about.
+type eventTargets struct {
+	// refs is the number of selected pods referring to the objects.
+	refs map[types.UID]int
+	// pods is the labels and the referred objects of the selected pods.
+	pods map[types.UID]eventTargetPod
+}
+
+type eventTargetPod struct {
+	labels  map[string]string
+	objects []types.UID
+}
+
+func newEventTargets() *eventTargets {
+	return &eventTargets{
+		refs: map[types.UID]int{},
+		pods: map[types.UID]eventTargetPod{},
+	}
+}
+
+// add adds the pod and its owners.
+func (t *eventTargets) add(pod *corev1.Pod) {
+	if target, exists := t.pods[pod.UID]; exists {
+		target.labels = pod.Labels
+		t.pods[pod.UID] = target
+		return
+	}
+
+	objects := []types.UID{pod.UID}
+	for _, owner := range pod.OwnerReferences {
+		objects = append(objects, owner.UID)
+	}
+	for _, uid := range objects {
+		t.refs[uid]++
+	}
+	t.pods[pod.UID] = eventTargetPod{labels: pod.Labels, objects: objects}
+}
+
+// removeUnmatched removes the pods not matching the selector, and the owners no longer referred.
+func (t *eventTargets) removeUnmatched(selector labels.Selector) {
+	for podUID, target := range t.pods {
+		if selector.Matches(labels.Set(target.labels)) {
+			continue
+		}
+		for _, uid := range target.objects {
+			t.refs[uid]--
+			if t.refs[uid] < 1 {
+				delete(t.refs, uid)
+			}
+		}
+		delete(t.pods, podUID)
+	}
+}
+
+func (t *eventTargets) contains(uid types.UID) bool {
+	_, exists := t.refs[uid]
+	return exists
+}
+
+// streamEvents emits the events about the tracked objects. In follow mode, it watches
+// the events until stopped.
+func (s *Streamer) streamEvents(
+	ctx context.Context,
+	source eventSource,
+	tracked func(uid types.UID) bool,
+	buf chan<- LogEntry,
+) {
+	since := s.sinceTime()
+
+	// emitted is the resource version of the emitted events by UID, to skip them when listed again
+	emitted := map[types.UID]string{}
+
+	// emit returns false when stopped
+	emit := func(obj runtime.Object) bool {
+		uid, entry, ok := source.convert(obj)
+		if !ok || !tracked(uid) || entry.Time.Before(since) {
+			return true
+		}
+		if accessor, err := meta.Accessor(obj); err == nil && accessor.GetUID() != "" {
+			if version, exists := emitted[accessor.GetUID()]; exists && version == accessor.GetResourceVersion() {
+				return true
+			}
+			emitted[accessor.GetUID()] = accessor.GetResourceVersion()
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case buf <- entry:
+			return true
+		}
+	}
+	// emitListed emits the listed events in time order, it returns false when stopped
+	emitListed := func(objs []runtime.Object) bool {
+		sortEvents(source, objs)
+		for _, obj := range objs {
+			if !emit(obj) {
+				return false
+			}
+		}
+
+		// the events not listed are gone, forget them
+		listed := make(map[types.UID]struct{}, len(objs))
+		for _, obj := range objs {
+			if accessor, err := meta.Accessor(obj); err == nil {
+				listed[accessor.GetUID()] = struct{}{}
+			}
+		}
+		for uid := range emitted {
+			if _, exists := listed[uid]; !exists {
+				delete(emitted, uid)
+			}
+		}
+		return true
+	}
+
+	objs, resourceVersion, err := source.list(ctx)
+	if err != nil {
+		s.logger.Log("failed to list events: %s", err)
+		return
+	}
+	if !emitListed(objs) {
+		return
+	}
+
+	if !s.follow {
+		return
+	}
+
+	var (
+		backoff = minRewatchBackoff
+		// expired is set once the resource version to watch from has expired
+		expired bool
+	)
+	// wait waits for the backoff before watching again, it returns false if stopped
+	wait := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRewatchBackoff {
+			backoff = maxRewatchBackoff
+		}
+		return true
+	}
+	// consume emits the watched events until the watch closes, it returns false if stopped
+	consume := func(watcher watch.Interface) bool {
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return false
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return true
+				}
+				switch event.Type {
+				case watch.Added, watch.Modified:
+				case watch.Error:
+					err := apierrors.FromObject(event.Object)
+					s.logger.Log("events watch error: %s", err)
+					expired = isResourceExpired(err)
+					return true
+				default:
+					continue
+				}
+
+				backoff = minRewatchBackoff
+				if accessor, err := meta.Accessor(event.Object); err == nil {
+					resourceVersion = accessor.GetResourceVersion()
+				}
+				if !emit(event.Object) {
+					return false
+				}
+			}
+		}
+	}
+
+	for {
+		if expired {
+			// the events since the resource version are gone, list again to catch up
+			s.logger.Log("relisting events")
+			objs, listedVersion, err := source.list(ctx)
+			if err != nil {
+				s.logger.Log("failed to list events: %s", err)
+				if !wait() {
+					return
+				}
+				continue
+			}
+			expired = false
+			resourceVersion = listedVersion
+			if !emitListed(objs) {
+				return
+			}
+		}
+
+		watcher, err := source.watch(ctx, resourceVersion)
+		if err != nil {
+			s.logger.Log("failed to watch events: %s", err)
+			expired = isResourceExpired(err)
+		} else if !consume(watcher) {
+			return
+		}
+
+		s.logger.Log("reconnecting events watcher")
+		s.hooks.WatchRestarted("events")
+		if !wait() {
+			return
+		}
+	}
+}
+
+// sortEvents sorts the events by the event time.
+func sortEvents(source eventSource, objs []runtime.Object) {
+	times := make(map[runtime.Object]time.Time, len(objs))
+	for _, obj := range objs {
+		_, entry, _ := source.convert(obj)
+		times[obj] = entry.Time
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return times[objs[i]].Before(times[objs[j]])
+	})
+}
+
+// isResourceExpired tells if the error is caused by watching from an expired resource version.
+func isResourceExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
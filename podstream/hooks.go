@@ -0,0 +1,65 @@
+package podstream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// EntryOutcome is the outcome of a read log line.
+type EntryOutcome string
+
+const (
+	// EntryEmitted is the outcome of entries emitted to the logs consumer.
+	EntryEmitted EntryOutcome = "emitted"
+	// EntryDropped is the outcome of lines dropped by rate limits or sampling.
+	EntryDropped EntryOutcome = "dropped"
+	// EntryFiltered is the outcome of lines not passing the log filter.
+	EntryFiltered EntryOutcome = "filtered"
+)
+
+// Hooks observes the streamer internals, e.g. to export them as metrics.
+// The methods are called from multiple goroutines and should return quickly.
+type Hooks interface {
+	// PodStreamStarted is called when a pod starts being streamed.
+	PodStreamStarted()
+	// PodStreamStopped is called when a pod stops being streamed.
+	PodStreamStopped()
+	// StreamOpenFailed is called when a pod log stream fails to open, with the
+	// Kubernetes API status reason of the error.
+	StreamOpenFailed(reason string)
+	// WatchRestarted is called when the watch of the resource, i.e. "pods" or "events",
+	// is restarted after the server closed it.
+	WatchRestarted(resource string)
+	// EntriesObserved is called with the number of entries of the outcome.
+	EntriesObserved(outcome EntryOutcome, count int)
+	// BufferObserved is called periodically with the length and the capacity of the buffer
+	// holding the entries read but not yet consumed.
+	BufferObserved(length int, capacity int)
+	// LogsConsumed is called after the logs consumer returns.
+	LogsConsumed(count int, latency time.Duration)
+}
+
+// noopHooks is the Hooks doing nothing.
+type noopHooks struct{}
+
+func (noopHooks) PodStreamStarted()                               {}
+func (noopHooks) PodStreamStopped()                               {}
+func (noopHooks) StreamOpenFailed(reason string)                  {}
+func (noopHooks) WatchRestarted(resource string)                  {}
+func (noopHooks) EntriesObserved(outcome EntryOutcome, count int) {}
+func (noopHooks) BufferObserved(length int, capacity int)         {}
+func (noopHooks) LogsConsumed(count int, latency time.Duration)   {}
+
+// streamErrorReason returns the reason of the stream error for reporting.
+func streamErrorReason(err error) string {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "Canceled"
+	}
+	if reason := apierrors.ReasonForError(err); reason != "" {
+		return string(reason)
+	}
+	return "Unknown"
+}
@@ -0,0 +1,221 @@
+package podstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	typedbatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// jobCompletionIndexAnnotation is the annotation holding the completion index of indexed Job pods.
+const jobCompletionIndexAnnotation = "batch.kubernetes.io/job-completion-index"
+
+// JobPodResult is the result of a Job pod.
+type JobPodResult struct {
+	// Name is the pod name.
+	Name string `json:"name"`
+	// CompletionIndex is the completion index of indexed Jobs. It is nil for non-indexed Jobs.
+	CompletionIndex *int `json:"completionIndex,omitempty"`
+	// Phase is the pod phase.
+	Phase corev1.PodPhase `json:"phase"`
+	// ExitCode is the first non-zero exit code of the terminated containers, or zero.
+	ExitCode int32 `json:"exitCode"`
+	// Reason is the termination reason of the container with the exit code, e.g. OOMKilled.
+	Reason string `json:"reason,omitempty"`
+}
+
+// JobResult is the result of a Job.
+type JobResult struct {
+	// Succeeded tells if the Job has completed successfully.
+	Succeeded bool `json:"succeeded"`
+	// Condition is the terminal condition of the Job, either Complete or Failed.
+	Condition batchv1.JobCondition `json:"condition"`
+	// Pods lists the results of the Job pods, including retries.
+	Pods []JobPodResult `json:"pods"`
+}
+
+// jobTerminalCondition returns the Complete or Failed condition of the Job if it has finished.
+func jobTerminalCondition(job *batchv1.Job) (batchv1.JobCondition, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed {
+			return condition, true
+		}
+	}
+	return batchv1.JobCondition{}, false
+}
+
+func jobPodResult(pod *corev1.Pod) JobPodResult {
+	result := JobPodResult{
+		Name:  pod.Name,
+		Phase: pod.Status.Phase,
+	}
+	if v, exists := pod.Annotations[jobCompletionIndexAnnotation]; exists {
+		if index, err := strconv.Atoi(v); err == nil {
+			result.CompletionIndex = &index
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			result.ExitCode = terminated.ExitCode
+			result.Reason = terminated.Reason
+			break
+		}
+	}
+	return result
+}
+
+// waitForJob waits until the Job reaches a terminal condition.
+func waitForJob(ctx context.Context, jobsClient typedbatchv1.JobInterface, name string) (*batchv1.Job, error) {
+	listOptions := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+	for {
+		jobs, err := jobsClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("list job: %w", err)
+		}
+		for idx := range jobs.Items {
+			if job := &jobs.Items[idx]; job.Name == name {
+				if _, finished := jobTerminalCondition(job); finished {
+					return job, nil
+				}
+			}
+		}
+
+		watcher, err := jobsClient.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   listOptions.FieldSelector,
+			ResourceVersion: jobs.ResourceVersion,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("watch job: %w", err)
+		}
+		job, err := func() (*batchv1.Job, error) {
+			defer watcher.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case event, ok := <-watcher.ResultChan():
+					if !ok {
+						// relist and watch again
+						return nil, nil
+					}
+					if event.Type == watch.Deleted {
+						return nil, fmt.Errorf("job %q has been deleted", name)
+					}
+					job, ok := event.Object.(*batchv1.Job)
+					if !ok || job.Name != name {
+						continue
+					}
+					if _, finished := jobTerminalCondition(job); finished {
+						return job, nil
+					}
+				}
+			}
+		}()
+		if err != nil || job != nil {
+			return job, err
+		}
+	}
+}
+
+// StreamJob follows the logs of all pods of the Job, including retries, until the Job
+// reaches a terminal condition. The logs of the tracked pods are consumed before it returns
+// the Job result.
+//
+// The pods selection is decided by the Job, options like FromSelectedPods are ignored.
+func StreamJob(
+	ctx context.Context,
+	jobsClient typedbatchv1.JobInterface,
+	podsClient typedcorev1.PodInterface,
+	name string,
+	options ...Option,
+) (*JobResult, error) {
+	job, err := jobsClient.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get job %q: %w", name, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("parse job selector: %w", err)
+	}
+
+	drain := make(chan struct{})
+	streamer, err := NewStreamer(
+		podsClient,
+		append(append([]Option{}, options...), FollowSelectedPods(selector.String()))...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	streamer.drain = drain
+
+	streamDone := make(chan error, 1)
+	go func() {
+		streamDone <- streamer.Run(ctx.Done())
+	}()
+
+	waitCtx, cancelWait := context.WithCancel(ctx)
+	defer cancelWait()
+	waitDone := make(chan struct{})
+	go func() {
+		defer close(waitDone)
+		job, err = waitForJob(waitCtx, jobsClient, name)
+	}()
+
+	select {
+	case streamErr := <-streamDone:
+		// the stream stopped before the Job finished, stop waiting for it
+		cancelWait()
+		<-waitDone
+		if streamErr != nil {
+			return nil, streamErr
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = fmt.Errorf("stream of job %q stopped before the job finished", name)
+		}
+		return nil, err
+	case <-waitDone:
+		close(drain)
+		if streamErr := <-streamDone; streamErr != nil {
+			return nil, streamErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	condition, _ := jobTerminalCondition(job)
+	result := &JobResult{
+		Succeeded: condition.Type == batchv1.JobComplete,
+		Condition: condition,
+	}
+
+	pods, err := podsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("list job pods: %w", err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	for idx := range pods.Items {
+		result.Pods = append(result.Pods, jobPodResult(&pods.Items[idx]))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package podstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStreamJob(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const namespace = "test"
+	client := fake.NewSimpleClientset()
+	jobsClient := client.BatchV1().Jobs(namespace)
+	podsClient := client.CoreV1().Pods(namespace)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "test-job", UID: "job-uid"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"controller-uid": "job-uid"}},
+		},
+	}
+	_, err := jobsClient.Create(ctx, job, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	newPod := func(name string, index string, exitCode int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				UID:         types.UID("uid-" + name),
+				Labels:      map[string]string{"controller-uid": "job-uid"},
+				Annotations: map[string]string{jobCompletionIndexAnnotation: index},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "main",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: "Error"},
+					},
+				}},
+			},
+		}
+	}
+	_, err = podsClient.Create(ctx, newPod("test-job-0", "0", 1), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	var (
+		mu         sync.Mutex
+		loadedLogs []LogEntry
+	)
+	go func() {
+		// the retry pod, then the job fails
+		time.Sleep(100 * time.Millisecond)
+		retryPod := newPod("test-job-1", "0", 137)
+		retryPod.CreationTimestamp = metav1.NewTime(time.Now().Add(time.Second))
+		retryPod.Status.ContainerStatuses[0].State.Terminated.Reason = "OOMKilled"
+		_, err := podsClient.Create(ctx, retryPod, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+		}
+		_, err = jobsClient.UpdateStatus(ctx, job, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+	}()
+
+	result, err := StreamJob(
+		ctx, jobsClient, podsClient, "test-job",
+		ConsumeLogsWithFunc(func(logs []LogEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			loadedLogs = append(loadedLogs, logs...)
+		}),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Err(), "should stop once the job finishes")
+
+	assert.False(t, result.Succeeded)
+	assert.Equal(t, "BackoffLimitExceeded", result.Condition.Reason)
+	if assert.Len(t, result.Pods, 2) {
+		assert.Equal(t, "test-job-0", result.Pods[0].Name)
+		assert.Equal(t, int32(1), result.Pods[0].ExitCode)
+		assert.Equal(t, 0, *result.Pods[0].CompletionIndex)
+
+		assert.Equal(t, "test-job-1", result.Pods[1].Name)
+		assert.Equal(t, int32(137), result.Pods[1].ExitCode)
+		assert.Equal(t, "OOMKilled", result.Pods[1].Reason)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	pods := map[string]bool{}
+	for _, entry := range loadedLogs {
+		pods[entry.Pod] = true
+	}
+	assert.Equal(t, map[string]bool{"test-job-0": true, "test-job-1": true}, pods)
+
+	_, err = StreamJob(ctx, jobsClient, podsClient, "missing")
+	assert.Error(t, err)
+
+	runningJob := job.DeepCopy()
+	runningJob.Name = "running-job"
+	runningJob.ResourceVersion = ""
+	runningJob.Status = batchv1.JobStatus{}
+	_, err = jobsClient.Create(ctx, runningJob, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = StreamJob(ctx, jobsClient, podsClient, "running-job", FromOutputStreams(StreamStdout))
+	assert.Error(t, err, "should stop waiting for the job once the stream fails")
+	assert.NoError(t, ctx.Err())
+}
@@ -0,0 +1,120 @@
+package podstream
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Level is the severity level parsed from a log line.
+type Level int
+
+const (
+	// LevelUnknown means the level cannot be parsed from the log line.
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = map[Level]string{
+	LevelUnknown: "unknown",
+	LevelTrace:   "trace",
+	LevelDebug:   "debug",
+	LevelInfo:    "info",
+	LevelWarn:    "warn",
+	LevelError:   "error",
+	LevelFatal:   "fatal",
+}
+
+func (l Level) String() string {
+	if name, exists := levelNames[l]; exists {
+		return name
+	}
+	return levelNames[LevelUnknown]
+}
+
+// levelKeys lists the structured log keys holding the level.
+var levelKeys = []string{"level", "lvl", "severity", "levelname", "log.level"}
+
+var (
+	// logfmtLevelPattern matches level in logfmt lines, e.g. "level=info msg=..."
+	logfmtLevelPattern = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)=("?)([a-z]+)`)
+	// klogLevelPattern matches klog style header, e.g. "I0501 10:00:00.000000 ..."
+	klogLevelPattern = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`)
+	// wordLevelPattern matches level words in the line prefix, e.g. "[ERROR] ..."
+	wordLevelPattern = regexp.MustCompile(`(?i)\b(trace|debug|info|warn|warning|error|err|fatal|panic|critical|crit)\b`)
+)
+
+// wordLevelPrefixLen limits the line prefix to search level words in.
+const wordLevelPrefixLen = 64
+
+// ParseLevelName parses a level name like "INFO" or "warning".
+func ParseLevelName(name string) Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return LevelTrace
+	case "debug", "dbug":
+		return LevelDebug
+	case "info", "information", "notice":
+		return LevelInfo
+	case "warn", "warning":
+		return LevelWarn
+	case "error", "err":
+		return LevelError
+	case "fatal", "panic", "critical", "crit", "dpanic", "emergency", "alert":
+		return LevelFatal
+	default:
+		return LevelUnknown
+	}
+}
+
+// ParseLevel parses the severity level from the log line.
+// It understands JSON, logfmt and klog formatted lines, and falls back to
+// level words near the start of the line.
+func ParseLevel(log string) Level {
+	if trimmed := strings.TrimSpace(log); strings.HasPrefix(trimmed, "{") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			for _, key := range levelKeys {
+				if v, ok := fields[key].(string); ok {
+					if level := ParseLevelName(v); level != LevelUnknown {
+						return level
+					}
+				}
+			}
+		}
+	}
+
+	if m := logfmtLevelPattern.FindStringSubmatch(log); m != nil {
+		if level := ParseLevelName(m[2]); level != LevelUnknown {
+			return level
+		}
+	}
+
+	if m := klogLevelPattern.FindStringSubmatch(log); m != nil {
+		switch m[1] {
+		case "I":
+			return LevelInfo
+		case "W":
+			return LevelWarn
+		case "E":
+			return LevelError
+		case "F":
+			return LevelFatal
+		}
+	}
+
+	prefix := log
+	if len(prefix) > wordLevelPrefixLen {
+		prefix = prefix[:wordLevelPrefixLen]
+	}
+	if m := wordLevelPattern.FindStringSubmatch(prefix); m != nil {
+		return ParseLevelName(m[1])
+	}
+
+	return LevelUnknown
+}
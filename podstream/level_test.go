@@ -0,0 +1,27 @@
+package podstream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		log      string
+		expected Level
+	}{
+		{log: `{"level":"error","msg":"failed"}`, expected: LevelError},
+		{log: `{"severity":"WARNING","message":"slow"}`, expected: LevelWarn},
+		{log: `time=2022-05-01T10:00:00Z level=debug msg="hello"`, expected: LevelDebug},
+		{log: `I0501 10:00:00.000000       1 main.go:10] started`, expected: LevelInfo},
+		{log: `E0501 10:00:00.000000       1 main.go:10] failed`, expected: LevelError},
+		{log: `2022/05/01 10:00:00 [WARN] disk is almost full`, expected: LevelWarn},
+		{log: `panic: runtime error: index out of range`, expected: LevelFatal},
+		{log: `hello world`, expected: LevelUnknown},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, ParseLevel(c.log), c.log)
+	}
+}
@@ -0,0 +1,139 @@
+package podstream
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit specifies the token bucket limits of a pod container log stream.
+// Zero rates mean no limit.
+type RateLimit struct {
+	// Entries is the max entries per second.
+	Entries float64
+	// EntriesBurst is the max entries allowed at once. Defaults to Entries rounded up.
+	EntriesBurst int
+	// Bytes is the max log bytes per second.
+	Bytes float64
+	// BytesBurst is the max log bytes allowed at once. Defaults to Bytes rounded up.
+	// Lines longer than the burst are counted as the burst.
+	BytesBurst int
+}
+
+func newLimiter(limit float64, burst int) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(limit)
+		if float64(burst) < limit {
+			burst++
+		}
+	}
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// adaptiveSampler samples low level entries when the volume goes beyond the target.
+type adaptiveSampler struct {
+	// target is the entries per second to keep before sampling.
+	target int
+
+	// window is the start of the current one second window by entry time.
+	window time.Time
+	// windowCount is the entries seen in the current window.
+	windowCount int
+	// prevCount is the entries seen in the previous window.
+	prevCount int
+	// sampled counts the low level entries seen since sampling.
+	sampled int
+}
+
+// sample returns the sample rate of the entry, or zero if the entry should be dropped.
+func (s *adaptiveSampler) sample(entry LogEntry) int {
+	window := entry.Time.Truncate(time.Second)
+	if !window.Equal(s.window) {
+		if window.Sub(s.window) == time.Second {
+			s.prevCount = s.windowCount
+		} else {
+			s.prevCount = 0
+		}
+		s.window = window
+		s.windowCount = 0
+	}
+	s.windowCount++
+
+	if level := ParseLevel(entry.Log); level >= LevelWarn {
+		// warnings and errors are always kept
+		return 1
+	}
+
+	count := s.windowCount
+	if s.prevCount > count {
+		count = s.prevCount
+	}
+	sampleRate := (count + s.target - 1) / s.target
+	if sampleRate <= 1 {
+		s.sampled = 0
+		return 1
+	}
+
+	s.sampled++
+	if s.sampled%sampleRate != 1 {
+		return 0
+	}
+	return sampleRate
+}
+
+// streamLimiter limits the entries of a pod container log stream.
+type streamLimiter struct {
+	now func() time.Time
+
+	sampler *adaptiveSampler
+	entries *rate.Limiter
+	bytes   *rate.Limiter
+
+	// dropped counts the entries dropped by rate limits.
+	dropped int64
+}
+
+func (s *Streamer) newStreamLimiter() *streamLimiter {
+	l := &streamLimiter{
+		now:     time.Now,
+		entries: newLimiter(s.rateLimit.Entries, s.rateLimit.EntriesBurst),
+		bytes:   newLimiter(s.rateLimit.Bytes, s.rateLimit.BytesBurst),
+	}
+	if s.sampleTarget > 0 {
+		l.sampler = &adaptiveSampler{target: s.sampleTarget}
+	}
+	return l
+}
+
+// allow tells if the entry should be consumed. It sets the sample rate of sampled entries.
+func (l *streamLimiter) allow(entry *LogEntry) bool {
+	if l.sampler != nil {
+		sampleRate := l.sampler.sample(*entry)
+		if sampleRate < 1 {
+			return false
+		}
+		if sampleRate > 1 {
+			entry.SampleRate = sampleRate
+		}
+	}
+
+	now := l.now()
+	if l.entries != nil && !l.entries.AllowN(now, 1) {
+		l.dropped++
+		return false
+	}
+	if l.bytes != nil {
+		n := len(entry.Log)
+		if n > l.bytes.Burst() {
+			n = l.bytes.Burst()
+		}
+		if !l.bytes.AllowN(now, n) {
+			l.dropped++
+			return false
+		}
+	}
+	return true
+}
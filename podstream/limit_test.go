@@ -0,0 +1,74 @@
+package podstream
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLimiter_RateLimit(t *testing.T) {
+	s := &Streamer{rateLimit: RateLimit{Entries: 2, Bytes: 10, BytesBurst: 12}}
+	limiter := s.newStreamLimiter()
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if limiter.allow(&LogEntry{Log: "abc"}) {
+			allowed++
+		}
+	}
+	assert.Equal(t, 2, allowed, "entries burst")
+	assert.Equal(t, int64(3), limiter.dropped)
+
+	now = now.Add(time.Second)
+	assert.True(t, limiter.allow(&LogEntry{Log: "abcdefghij"}))
+	assert.False(t, limiter.allow(&LogEntry{Log: "abcdefghij"}), "bytes limit")
+}
+
+func TestStreamLimiter_Sample(t *testing.T) {
+	s := &Streamer{sampleTarget: 10}
+	limiter := s.newStreamLimiter()
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	var (
+		kept        []LogEntry
+		errorsCount int
+	)
+	for i := 0; i < 100; i++ {
+		entry := LogEntry{
+			Time: start.Add(time.Duration(i) * 10 * time.Millisecond),
+			Log:  fmt.Sprintf("level=info msg=request %d", i),
+		}
+		if i%20 == 0 {
+			entry.Log = fmt.Sprintf("level=error msg=request %d", i)
+		}
+		if limiter.allow(&entry) {
+			kept = append(kept, entry)
+			if entry.SampleRate == 0 && i%20 == 0 {
+				errorsCount++
+			}
+		}
+	}
+
+	assert.Equal(t, 5, errorsCount, "errors should always be kept")
+	assert.Less(t, len(kept), 40)
+
+	// corrected count should approximate the volume
+	var total int
+	for _, entry := range kept {
+		if entry.SampleRate > 0 {
+			total += entry.SampleRate
+		} else {
+			total++
+		}
+	}
+	assert.InDelta(t, 100, total, 40)
+
+	// volume back to normal in the next windows
+	entry := LogEntry{Time: start.Add(5 * time.Second), Log: "level=info msg=idle"}
+	assert.True(t, limiter.allow(&entry))
+	assert.Zero(t, entry.SampleRate)
+}
@@ -0,0 +1,234 @@
+package logdiff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/b4fun/kubekit/podstream/logpattern"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	sideBaseline = iota
+	sideCandidate
+)
+
+// sideStats collects the logs statistics of one side.
+type sideStats struct {
+	labelSelector string
+	pods          map[string]struct{}
+	lines         int64
+	bytes         int64
+	levels        map[podstream.Level]int64
+	templates     map[int]int64
+}
+
+func newSideStats(labelSelector string) *sideStats {
+	return &sideStats{
+		labelSelector: labelSelector,
+		pods:          map[string]struct{}{},
+		levels:        map[podstream.Level]int64{},
+		templates:     map[int]int64{},
+	}
+}
+
+func (s *sideStats) rate(count int64) float64 {
+	if len(s.pods) < 1 {
+		return 0
+	}
+	return float64(count) / float64(len(s.pods))
+}
+
+type comparer struct {
+	logger logger.Logger
+
+	// window is the time window to compare logs in.
+	window time.Duration
+
+	// changeFactor is the ratio between rates to be reported as changed.
+	changeFactor float64
+
+	streamOptions  []podstream.Option
+	patternOptions []logpattern.Option
+
+	// analyzer groups logs of both sides into templates, so templates IDs are comparable.
+	analyzer *logpattern.Analyzer
+
+	mu    sync.Mutex
+	sides [2]*sideStats
+}
+
+func newComparer(baselineSelector string, candidateSelector string, options ...Option) (*comparer, error) {
+	c := &comparer{
+		logger:       logger.NoOp,
+		window:       10 * time.Minute,
+		changeFactor: 2,
+		sides: [2]*sideStats{
+			newSideStats(baselineSelector),
+			newSideStats(candidateSelector),
+		},
+	}
+	for _, opt := range options {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.logger == nil {
+		c.logger = logger.NoOp
+	}
+
+	analyzer, err := logpattern.NewAnalyzer(
+		append([]logpattern.Option{logpattern.WithoutSpikeDetection()}, c.patternOptions...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create pattern analyzer: %w", err)
+	}
+	c.analyzer = analyzer
+
+	return c, nil
+}
+
+// observe adds the logs to the side.
+func (c *comparer) observe(side int, logs []podstream.LogEntry) {
+	for _, entry := range logs {
+		templateID, _ := c.analyzer.Observe(entry)
+
+		c.mu.Lock()
+		stats := c.sides[side]
+		stats.pods[entry.Namespace+"/"+entry.Pod] = struct{}{}
+		stats.lines++
+		stats.bytes += int64(len(entry.Log))
+		stats.levels[podstream.ParseLevel(entry.Log)]++
+		stats.templates[templateID]++
+		c.mu.Unlock()
+	}
+}
+
+func (c *comparer) diff(baselineCount int64, candidateCount int64) Diff {
+	baseline, candidate := c.sides[sideBaseline], c.sides[sideCandidate]
+
+	d := Diff{
+		BaselineCount:  baselineCount,
+		CandidateCount: candidateCount,
+		BaselineRate:   baseline.rate(baselineCount),
+		CandidateRate:  candidate.rate(candidateCount),
+		Change:         ChangeUnchanged,
+	}
+	switch {
+	case baselineCount == 0 && candidateCount == 0:
+	case baselineCount == 0:
+		d.Change = ChangeAdded
+	case candidateCount == 0:
+		d.Change = ChangeRemoved
+	case d.CandidateRate >= c.changeFactor*d.BaselineRate:
+		d.Change = ChangeIncreased
+	case d.BaselineRate >= c.changeFactor*d.CandidateRate:
+		d.Change = ChangeDecreased
+	}
+	return d
+}
+
+func summarize(s *sideStats) SideSummary {
+	return SideSummary{
+		LabelSelector: s.labelSelector,
+		Pods:          len(s.pods),
+		Lines:         s.lines,
+		Bytes:         s.bytes,
+		LinesPerPod:   s.rate(s.lines),
+	}
+}
+
+func (c *comparer) report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	baseline, candidate := c.sides[sideBaseline], c.sides[sideCandidate]
+	report := &Report{
+		Baseline:  summarize(baseline),
+		Candidate: summarize(candidate),
+		Volume:    c.diff(baseline.lines, candidate.lines),
+	}
+
+	for level := podstream.LevelUnknown; level <= podstream.LevelFatal; level++ {
+		if baseline.levels[level] == 0 && candidate.levels[level] == 0 {
+			continue
+		}
+		report.Levels = append(report.Levels, LevelDiff{
+			Diff:  c.diff(baseline.levels[level], candidate.levels[level]),
+			Level: level.String(),
+		})
+	}
+
+	for _, template := range c.analyzer.Templates() {
+		report.Templates = append(report.Templates, TemplateDiff{
+			Diff:       c.diff(baseline.templates[template.ID], candidate.templates[template.ID]),
+			TemplateID: template.ID,
+			Pattern:    template.Pattern,
+			Examples:   template.Examples,
+		})
+	}
+	sort.Slice(report.Templates, func(i, j int) bool {
+		return report.Templates[i].TemplateID < report.Templates[j].TemplateID
+	})
+
+	return report
+}
+
+// Compare streams the logs of the baseline and candidate pods side by side over the same
+// time window, and reports the changes of message templates, levels and volume between them.
+//
+// The selectors can be any two label selectors; see DeploymentSelectors for comparing the
+// revisions of a Deployment.
+func Compare(
+	ctx context.Context,
+	podsClient typedcorev1.PodInterface,
+	baselineSelector string,
+	candidateSelector string,
+	options ...Option,
+) (*Report, error) {
+	c, err := newComparer(baselineSelector, candidateSelector, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		errs [2]error
+	)
+	for side, stats := range c.sides {
+		wg.Add(1)
+		go func(side int, labelSelector string) {
+			defer wg.Done()
+
+			streamOptions := append(
+				append([]podstream.Option{}, c.streamOptions...),
+				podstream.WithLogger(c.logger),
+				// also resets the follow flag from the stream options, so the stream finishes
+				podstream.FromSelectedPods(labelSelector),
+				podstream.Since(c.window),
+				podstream.ConsumeLogsWithFunc(func(logs []podstream.LogEntry) {
+					c.observe(side, logs)
+				}),
+			)
+			errs[side] = podstream.Stream(ctx.Done(), podsClient, streamOptions...)
+		}(side, stats.labelSelector)
+	}
+	wg.Wait()
+
+	if errs[sideBaseline] != nil {
+		return nil, fmt.Errorf("stream baseline: %w", errs[sideBaseline])
+	}
+	if errs[sideCandidate] != nil {
+		return nil, fmt.Errorf("stream candidate: %w", errs[sideCandidate])
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.report(), nil
+}
@@ -0,0 +1,147 @@
+package logdiff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testEntry(pod string, log string) podstream.LogEntry {
+	return podstream.LogEntry{Time: time.Now(), Namespace: "ns", Pod: pod, Log: log}
+}
+
+func TestComparer_Report(t *testing.T) {
+	c, err := newComparer("rev=old", "rev=new")
+	assert.NoError(t, err)
+
+	var baselineLogs []podstream.LogEntry
+	for i := 0; i < 4; i++ {
+		pod := fmt.Sprintf("old-%d", i%2)
+		baselineLogs = append(baselineLogs,
+			testEntry(pod, fmt.Sprintf("level=info msg=handled request %d", i)),
+			testEntry(pod, "level=info msg=cache warmed"),
+		)
+	}
+	c.observe(sideBaseline, baselineLogs)
+
+	var candidateLogs []podstream.LogEntry
+	for i := 0; i < 6; i++ {
+		candidateLogs = append(candidateLogs,
+			testEntry("new-0", fmt.Sprintf("level=info msg=handled request %d", i)),
+			testEntry("new-0", fmt.Sprintf("level=error msg=db timeout after %dms", i)),
+		)
+	}
+	c.observe(sideCandidate, candidateLogs)
+
+	report := c.report()
+
+	assert.Equal(t, 2, report.Baseline.Pods)
+	assert.Equal(t, int64(8), report.Baseline.Lines)
+	assert.Equal(t, 1, report.Candidate.Pods)
+	assert.Equal(t, int64(12), report.Candidate.Lines)
+	assert.Equal(t, ChangeIncreased, report.Volume.Change)
+
+	changes := map[string]Change{}
+	for _, template := range report.Templates {
+		changes[template.Pattern] = template.Change
+	}
+	assert.Equal(t, map[string]Change{
+		"level=info msg=handled request <*>":   ChangeIncreased,
+		"level=info msg=cache warmed":          ChangeRemoved,
+		"level=error msg=db timeout after <*>": ChangeAdded,
+	}, changes)
+	assert.Len(t, report.ChangedTemplates(), 3)
+
+	levels := map[string]Change{}
+	for _, level := range report.Levels {
+		levels[level.Level] = level.Change
+	}
+	assert.Equal(t, map[string]Change{"info": ChangeUnchanged, "error": ChangeAdded}, levels)
+}
+
+func TestCompare(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := fake.NewSimpleClientset()
+	for i, rev := range []string{"old", "old", "new"} {
+		_, err := client.CoreV1().Pods("ns").Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      fmt.Sprintf("pod-%d", i),
+				UID:       types.UID(fmt.Sprintf("pod-%d", i)),
+				Labels:    map[string]string{"app": "test", "rev": rev},
+			},
+		}, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	report, err := Compare(ctx, client.CoreV1().Pods("ns"), "app=test,rev=old", "app=test,rev=new")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, report.Baseline.Pods)
+	assert.Equal(t, 1, report.Candidate.Pods)
+	assert.Equal(t, ChangeUnchanged, report.Volume.Change)
+	assert.Empty(t, report.ChangedTemplates())
+
+	report, err = Compare(
+		ctx, client.CoreV1().Pods("ns"), "app=test,rev=old", "app=test,rev=new",
+		WithStreamOptions(podstream.FollowSelectedPods("app=test")),
+	)
+	assert.NoError(t, err, "Compare should not follow the logs")
+	assert.Equal(t, 2, report.Baseline.Pods)
+}
+
+func TestDeploymentSelectors(t *testing.T) {
+	ctx := context.Background()
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app", UID: types.UID("deploy-uid")},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+	}
+	isController := true
+	newReplicaSet := func(hash string, revision string, replicas int32) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "ns",
+				Name:        "app-" + hash,
+				Labels:      map[string]string{"app": "test", podTemplateHashLabel: hash},
+				Annotations: map[string]string{revisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       deployment.Name,
+					UID:        deployment.UID,
+					Controller: &isController,
+				}},
+			},
+			Status: appsv1.ReplicaSetStatus{Replicas: replicas},
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		deployment,
+		newReplicaSet("aaa", "1", 0),
+		newReplicaSet("bbb", "2", 3),
+		newReplicaSet("ccc", "3", 1),
+	)
+
+	baseline, candidate, err := DeploymentSelectors(ctx, client.AppsV1(), "ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, "app=test,pod-template-hash=bbb", baseline)
+	assert.Equal(t, "app=test,pod-template-hash=ccc", candidate)
+
+	_, _, err = DeploymentSelectors(ctx, client.AppsV1(), "ns", "missing")
+	assert.Error(t, err)
+}
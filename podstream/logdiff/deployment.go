@@ -0,0 +1,100 @@
+package logdiff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+)
+
+const (
+	// revisionAnnotation is the annotation holding the revision of a deployment's replica set.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// podTemplateHashLabel is the label distinguishing pods of different replica sets.
+	podTemplateHashLabel = appsv1.DefaultDeploymentUniqueLabelKey
+)
+
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	v, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// DeploymentSelectors resolves the pods label selectors of the previous and the newest
+// revisions of a Deployment. Previous revisions with running replicas are preferred, so the
+// baseline is the revision being replaced during a rollout.
+func DeploymentSelectors(
+	ctx context.Context,
+	appsClient typedappsv1.AppsV1Interface,
+	namespace string,
+	name string,
+) (baselineSelector string, candidateSelector string, err error) {
+	deployment, err := appsClient.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("get deployment %q: %w", name, err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return "", "", fmt.Errorf("parse deployment selector: %w", err)
+	}
+
+	rsList, err := appsClient.ReplicaSets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("list replica sets: %w", err)
+	}
+
+	var owned []*appsv1.ReplicaSet
+	for idx := range rsList.Items {
+		rs := &rsList.Items[idx]
+		if metav1.IsControlledBy(rs, deployment) {
+			owned = append(owned, rs)
+		}
+	}
+	if len(owned) < 2 {
+		return "", "", fmt.Errorf("deployment %q has %d revisions, expected at least 2", name, len(owned))
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return replicaSetRevision(owned[i]) > replicaSetRevision(owned[j])
+	})
+
+	candidate := owned[0]
+	baseline := owned[1]
+	for _, rs := range owned[1:] {
+		if rs.Status.Replicas > 0 {
+			baseline = rs
+			break
+		}
+	}
+
+	revisionSelector := func(rs *appsv1.ReplicaSet) (string, error) {
+		hash, exists := rs.Labels[podTemplateHashLabel]
+		if !exists {
+			return "", fmt.Errorf("replica set %q has no %s label", rs.Name, podTemplateHashLabel)
+		}
+
+		requirement, err := labels.NewRequirement(podTemplateHashLabel, "=", []string{hash})
+		if err != nil {
+			return "", err
+		}
+		return selector.Add(*requirement).String(), nil
+	}
+
+	if baselineSelector, err = revisionSelector(baseline); err != nil {
+		return "", "", err
+	}
+	if candidateSelector, err = revisionSelector(candidate); err != nil {
+		return "", "", err
+	}
+	return baselineSelector, candidateSelector, nil
+}
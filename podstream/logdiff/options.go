@@ -0,0 +1,61 @@
+package logdiff
+
+import (
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/b4fun/kubekit/podstream/logpattern"
+)
+
+// WithLogger sets the logger to be used by the comparison.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(comparer *comparer) error {
+		comparer.logger = logger
+		return nil
+	}
+}
+
+// WithWindow sets the time window to compare logs in. Defaults to 10 minutes.
+func WithWindow(window time.Duration) Option {
+	return func(comparer *comparer) error {
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+
+		comparer.window = window
+		return nil
+	}
+}
+
+// WithChangeFactor sets the ratio between rates to be reported as increased or decreased.
+// Defaults to 2.
+func WithChangeFactor(factor float64) Option {
+	return func(comparer *comparer) error {
+		if factor <= 1 {
+			return errors.New("change factor must be greater than 1")
+		}
+
+		comparer.changeFactor = factor
+		return nil
+	}
+}
+
+// WithStreamOptions sets extra podstream options for streaming both sides,
+// for example podstream.FromContainer. The pods selection and the logs window are set by
+// Compare, and Compare never follows the logs.
+func WithStreamOptions(options ...podstream.Option) Option {
+	return func(comparer *comparer) error {
+		comparer.streamOptions = append(comparer.streamOptions, options...)
+		return nil
+	}
+}
+
+// WithPatternOptions sets the options for the template analyzer.
+func WithPatternOptions(options ...logpattern.Option) Option {
+	return func(comparer *comparer) error {
+		comparer.patternOptions = append(comparer.patternOptions, options...)
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package logdiff
+
+// Change describes how a metric changed from the baseline to the candidate.
+type Change string
+
+const (
+	ChangeUnchanged Change = "unchanged"
+	// ChangeAdded means it only shows up in the candidate.
+	ChangeAdded Change = "added"
+	// ChangeRemoved means it only shows up in the baseline.
+	ChangeRemoved   Change = "removed"
+	ChangeIncreased Change = "increased"
+	ChangeDecreased Change = "decreased"
+)
+
+// SideSummary summarizes the logs of one side of the comparison.
+type SideSummary struct {
+	// LabelSelector is the pods label selector of the side.
+	LabelSelector string `json:"labelSelector"`
+	// Pods is the number of pods emitting logs.
+	Pods int `json:"pods"`
+	// Lines is the number of log lines.
+	Lines int64 `json:"lines"`
+	// Bytes is the total size of log lines.
+	Bytes int64 `json:"bytes"`
+	// LinesPerPod is the average log lines per pod.
+	LinesPerPod float64 `json:"linesPerPod"`
+}
+
+// Diff compares a count between the baseline and the candidate.
+// Rates are the counts per pod, so sides with different replicas can be compared.
+type Diff struct {
+	BaselineCount  int64   `json:"baselineCount"`
+	CandidateCount int64   `json:"candidateCount"`
+	BaselineRate   float64 `json:"baselineRate"`
+	CandidateRate  float64 `json:"candidateRate"`
+	Change         Change  `json:"change"`
+}
+
+// TemplateDiff compares the occurrences of a message template.
+type TemplateDiff struct {
+	Diff
+
+	// TemplateID is the template ID.
+	TemplateID int `json:"templateID"`
+	// Pattern is the template pattern.
+	Pattern string `json:"pattern"`
+	// Examples lists example lines of the template.
+	Examples []string `json:"examples"`
+}
+
+// LevelDiff compares the occurrences of a log level.
+type LevelDiff struct {
+	Diff
+
+	// Level is the log level name.
+	Level string `json:"level"`
+}
+
+// Report is the comparison result.
+type Report struct {
+	Baseline  SideSummary `json:"baseline"`
+	Candidate SideSummary `json:"candidate"`
+
+	// Volume compares the log lines.
+	Volume Diff `json:"volume"`
+	// Levels compares the log lines by level.
+	Levels []LevelDiff `json:"levels"`
+	// Templates compares the log lines by message template, ordered by template ID.
+	Templates []TemplateDiff `json:"templates"`
+}
+
+// ChangedTemplates returns the templates with changes.
+func (r *Report) ChangedTemplates() []TemplateDiff {
+	var rv []TemplateDiff
+	for _, t := range r.Templates {
+		if t.Change != ChangeUnchanged {
+			rv = append(rv, t)
+		}
+	}
+	return rv
+}
+
+// Option specifies options for configuring the comparison.
+type Option func(comparer *comparer) error
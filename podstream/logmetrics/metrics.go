@@ -0,0 +1,302 @@
+package logmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Labels added to all the derived metrics.
+const (
+	LabelNamespace = "namespace"
+	LabelPod       = "pod"
+	LabelContainer = "container"
+)
+
+var baseLabels = []string{LabelNamespace, LabelPod, LabelContainer}
+
+// Metrics derives Prometheus metrics from log entries.
+//
+// It can be used as a podstream.LogEntryTransformer which passes the logs through unchanged,
+// or as a podstream.LogEntryConsumer.
+type Metrics struct {
+	logger logger.Logger
+
+	registry *prometheus.Registry
+
+	rules []*compiledRule
+}
+
+var (
+	_ podstream.LogEntryTransformer = (*Metrics)(nil)
+	_ podstream.LogEntryConsumer    = (*Metrics)(nil)
+)
+
+type compiledRule struct {
+	Rule
+
+	pattern *regexp.Regexp
+
+	counter   *prometheus.CounterVec
+	histogram *prometheus.HistogramVec
+}
+
+// New creates log metrics with the rules and registers them to the registry.
+func New(rules []Rule, options ...Option) (*Metrics, error) {
+	metrics := &Metrics{
+		logger: logger.NoOp,
+	}
+	for _, opt := range options {
+		if err := opt(metrics); err != nil {
+			return nil, err
+		}
+	}
+	if metrics.logger == nil {
+		metrics.logger = logger.NoOp
+	}
+	if metrics.registry == nil {
+		metrics.registry = prometheus.NewRegistry()
+	}
+
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+
+		var collector prometheus.Collector = compiled.counter
+		if compiled.histogram != nil {
+			collector = compiled.histogram
+		}
+		if err := metrics.registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("register rule %q: %w", rule.Name, err)
+		}
+
+		metrics.rules = append(metrics.rules, compiled)
+	}
+
+	return metrics, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if rule.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if rule.Regex == "" && len(rule.Fields) < 1 {
+		return nil, fmt.Errorf("regex or fields is required")
+	}
+
+	compiled := &compiledRule{Rule: rule}
+	if rule.Regex != "" {
+		pattern, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, err
+		}
+		compiled.pattern = pattern
+	}
+
+	// labels and value should be resolvable from either the capture groups or the fields
+	known := func(name string) bool {
+		if len(rule.Fields) > 0 {
+			// field based rules can read any field of the matched line
+			return true
+		}
+		return compiled.pattern != nil && compiled.pattern.SubexpIndex(name) > 0
+	}
+	for _, label := range rule.Labels {
+		if !known(label) {
+			return nil, fmt.Errorf("label %q is neither a capture group nor a field", label)
+		}
+	}
+	if rule.Value != "" && !known(rule.Value) {
+		return nil, fmt.Errorf("value %q is neither a capture group nor a field", rule.Value)
+	}
+
+	labelNames := append(append([]string{}, baseLabels...), rule.Labels...)
+	switch rule.Kind {
+	case "", KindCounter:
+		compiled.counter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: rule.Name, Help: help(rule)},
+			labelNames,
+		)
+	case KindHistogram:
+		if rule.Value == "" {
+			return nil, fmt.Errorf("value is required for histogram")
+		}
+		buckets := rule.Buckets
+		if len(buckets) < 1 {
+			buckets = prometheus.DefBuckets
+		}
+		compiled.histogram = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: rule.Name, Help: help(rule), Buckets: buckets},
+			labelNames,
+		)
+	default:
+		return nil, fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+
+	return compiled, nil
+}
+
+func help(rule Rule) string {
+	if rule.Help != "" {
+		return rule.Help
+	}
+	return fmt.Sprintf("Derived from podstream logs by rule %s.", rule.Name)
+}
+
+// parseValue parses a number or a duration in seconds.
+func parseValue(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return d.Seconds(), nil
+}
+
+// fieldString converts a JSON field value to string.
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// match matches the log line. It returns the values of the captured groups and fields.
+func (r *compiledRule) match(log string, fields map[string]interface{}) (map[string]string, bool) {
+	values := map[string]string{}
+
+	if len(r.Fields) > 0 {
+		if fields == nil {
+			return nil, false
+		}
+		for name, expected := range r.Fields {
+			v, exists := fields[name]
+			if !exists {
+				return nil, false
+			}
+			s := fieldString(v)
+			if expected != "" && s != expected {
+				return nil, false
+			}
+			values[name] = s
+		}
+		for _, name := range append([]string{r.Value}, r.Labels...) {
+			if v, exists := fields[name]; exists && name != "" {
+				values[name] = fieldString(v)
+			}
+		}
+	}
+
+	if r.pattern != nil {
+		m := r.pattern.FindStringSubmatch(log)
+		if m == nil {
+			return nil, false
+		}
+		for idx, name := range r.pattern.SubexpNames() {
+			if name != "" {
+				values[name] = m[idx]
+			}
+		}
+	}
+
+	return values, true
+}
+
+func (r *compiledRule) apply(entry podstream.LogEntry, fields map[string]interface{}) error {
+	values, ok := r.match(entry.Log, fields)
+	if !ok {
+		return nil
+	}
+
+	labelValues := []string{entry.Namespace, entry.Pod, entry.Container}
+	for _, label := range r.Labels {
+		labelValues = append(labelValues, values[label])
+	}
+
+	value := 1.0
+	if r.Value != "" {
+		v, err := parseValue(values[r.Value])
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+
+	if r.histogram != nil {
+		r.histogram.WithLabelValues(labelValues...).Observe(value)
+		return nil
+	}
+	if value < 0 {
+		return fmt.Errorf("negative counter value %v", value)
+	}
+	r.counter.WithLabelValues(labelValues...).Add(value)
+	return nil
+}
+
+// decodeFields decodes the JSON log line. It returns nil for non-JSON lines.
+func decodeFields(log string) map[string]interface{} {
+	trimmed := strings.TrimSpace(log)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// Observe derives metrics from the log entry.
+func (m *Metrics) Observe(entry podstream.LogEntry) {
+	fields := decodeFields(entry.Log)
+	for _, rule := range m.rules {
+		if err := rule.apply(entry, fields); err != nil {
+			m.logger.Log("rule %s: %s", rule.Name, err)
+		}
+	}
+}
+
+func (m *Metrics) TransformLogs(logs []podstream.LogEntry) []podstream.LogEntry {
+	for _, entry := range logs {
+		m.Observe(entry)
+	}
+	return logs
+}
+
+func (m *Metrics) OnLogs(logs []podstream.LogEntry) {
+	m.TransformLogs(logs)
+}
+
+// Registry returns the registry holding the derived metrics.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns a http handler serving the derived metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,101 @@
+package logmetrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func entry(pod string, log string) podstream.LogEntry {
+	return podstream.LogEntry{
+		Time:      time.Now(),
+		Log:       log,
+		Namespace: "ns",
+		Pod:       pod,
+		Container: "app",
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics, err := New([]Rule{
+		{
+			Name:   "app_errors_total",
+			Regex:  `level=error .*code=(?P<code>\w+)`,
+			Labels: []string{"code"},
+		},
+		{
+			Name:    "app_request_duration_seconds",
+			Kind:    KindHistogram,
+			Fields:  map[string]string{"msg": "request done", "latency": ""},
+			Labels:  []string{"method"},
+			Value:   "latency",
+			Buckets: []float64{0.1, 1},
+		},
+	})
+	assert.NoError(t, err)
+
+	logs := []podstream.LogEntry{
+		entry("pod-1", `level=error msg="failed" code=E1`),
+		entry("pod-1", `level=error msg="failed" code=E1`),
+		entry("pod-2", `level=error msg="failed" code=E2`),
+		entry("pod-1", `level=info msg="ok"`),
+		entry("pod-1", `{"msg":"request done","method":"GET","latency":"250ms"}`),
+		entry("pod-1", `{"msg":"request done","method":"GET","latency":0.05}`),
+		entry("pod-1", `{"msg":"request started","method":"GET"}`),
+	}
+	assert.Equal(t, logs, metrics.TransformLogs(logs), "logs should pass through unchanged")
+
+	errors := metrics.rules[0].counter
+	assert.Equal(t, 2.0, testutil.ToFloat64(errors.WithLabelValues("ns", "pod-1", "app", "E1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("ns", "pod-2", "app", "E2")))
+
+	expected := `
+# HELP app_request_duration_seconds Derived from podstream logs by rule app_request_duration_seconds.
+# TYPE app_request_duration_seconds histogram
+app_request_duration_seconds_bucket{container="app",method="GET",namespace="ns",pod="pod-1",le="0.1"} 1
+app_request_duration_seconds_bucket{container="app",method="GET",namespace="ns",pod="pod-1",le="1"} 2
+app_request_duration_seconds_bucket{container="app",method="GET",namespace="ns",pod="pod-1",le="+Inf"} 2
+app_request_duration_seconds_sum{container="app",method="GET",namespace="ns",pod="pod-1"} 0.3
+app_request_duration_seconds_count{container="app",method="GET",namespace="ns",pod="pod-1"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(
+		metrics.Registry(), strings.NewReader(expected), "app_request_duration_seconds",
+	))
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "app_errors_total")
+}
+
+func TestMetrics_InvalidRules(t *testing.T) {
+	cases := []Rule{
+		{Regex: "foo"},
+		{Name: "no_match"},
+		{Name: "bad_regex", Regex: "("},
+		{Name: "unknown_label", Regex: "foo", Labels: []string{"bar"}},
+		{Name: "histogram_without_value", Regex: "foo", Kind: KindHistogram},
+		{Name: "unknown_kind", Regex: "foo", Kind: "gauge"},
+	}
+
+	for _, rule := range cases {
+		_, err := New([]Rule{rule})
+		assert.Error(t, err, rule.Name)
+	}
+}
+
+func TestMetrics_SharedRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	rules := []Rule{{Name: "test_total", Regex: "test"}}
+
+	_, err := New(rules, WithRegistry(registry))
+	assert.NoError(t, err)
+
+	_, err = New(rules, WithRegistry(registry))
+	assert.Error(t, err, "duplicated metrics should fail to register")
+}
@@ -0,0 +1,29 @@
+package logmetrics
+
+import (
+	"errors"
+
+	"github.com/b4fun/kubekit"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithLogger sets the logger to be used by the log metrics.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(metrics *Metrics) error {
+		metrics.logger = logger
+		return nil
+	}
+}
+
+// WithRegistry sets the registry to register the metrics to.
+// Defaults to a new registry.
+func WithRegistry(registry *prometheus.Registry) Option {
+	return func(metrics *Metrics) error {
+		if registry == nil {
+			return errors.New("registry is required")
+		}
+
+		metrics.registry = registry
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package logmetrics
+
+// Kind is the kind of metric a rule produces.
+type Kind string
+
+const (
+	// KindCounter counts the matched lines.
+	KindCounter Kind = "counter"
+	// KindHistogram observes values captured from the matched lines.
+	KindHistogram Kind = "histogram"
+)
+
+// Rule derives a metric from log lines.
+//
+// A line matches the rule if it matches the Regex and has all the Fields. Matching lines
+// increment the counter or observe the histogram, labelled by namespace, pod, container and
+// the values named in Labels.
+type Rule struct {
+	// Name is the metric name.
+	Name string
+	// Help is the metric help text.
+	Help string
+	// Kind is the metric kind. Defaults to KindCounter.
+	Kind Kind
+	// Regex is the pattern to match the line. Named capture groups can be used in Labels and Value.
+	Regex string
+	// Fields matches JSON lines having the fields with the values. An empty value matches
+	// any value of the field. Any field of the matched lines can be used in Labels and Value.
+	Fields map[string]string
+	// Labels lists the capture group names or JSON field names to add as labels.
+	Labels []string
+	// Value is the capture group name or JSON field name holding the value.
+	// It is required for histograms. Counters are incremented by the value if set, otherwise by 1.
+	// Values can be numbers or durations like "120ms"; durations are converted to seconds.
+	Value string
+	// Buckets are the histogram buckets. Defaults to prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Option specifies options for configuring the log metrics.
+type Option func(metrics *Metrics) error
@@ -0,0 +1,231 @@
+package logpattern
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// templateStats tracks the occurrences of a template.
+type templateStats struct {
+	id         int
+	count      int64
+	countByPod map[string]int64
+	examples   []string
+	firstSeen  time.Time
+	lastSeen   time.Time
+
+	// window is the start of the current spike detection window.
+	window time.Time
+	// windowCount is the occurrences in the current window.
+	windowCount int64
+	// windows is the number of windows completed since the template shows up.
+	windows int
+	// baseline is the moving average of occurrences per window.
+	baseline float64
+	// spiked tells if spike has been raised in the current window.
+	spiked bool
+}
+
+// Analyzer is a podstream.LogEntryConsumer that groups log messages into templates online,
+// and raises events when new or sharply spiking templates show up.
+type Analyzer struct {
+	drain *drain
+
+	// maxExamples limits the example lines kept per template.
+	maxExamples int
+
+	// warmup suppresses new template events for templates first seen within the duration
+	// since the first log entry.
+	warmup time.Duration
+
+	// spikeWindow is the window to count occurrences in for spike detection.
+	// Zero disables spike detection.
+	spikeWindow time.Duration
+	// spikeFactor is the ratio of the window occurrences to the baseline to be a spike.
+	spikeFactor float64
+	// spikeMinCount is the min window occurrences to be a spike.
+	spikeMinCount int64
+
+	onEvent EventHandler
+
+	mu        sync.Mutex
+	clusters  []*cluster
+	startedAt time.Time
+}
+
+var _ podstream.LogEntryConsumer = (*Analyzer)(nil)
+
+// NewAnalyzer creates a log pattern analyzer.
+func NewAnalyzer(options ...Option) (*Analyzer, error) {
+	analyzer := &Analyzer{
+		drain:         newDrain(4, 100, 0.4),
+		maxExamples:   3,
+		spikeWindow:   1 * time.Minute,
+		spikeFactor:   5,
+		spikeMinCount: 10,
+	}
+	for _, opt := range options {
+		if err := opt(analyzer); err != nil {
+			return nil, err
+		}
+	}
+
+	return analyzer, nil
+}
+
+func podKey(entry podstream.LogEntry) string {
+	if entry.Namespace == "" {
+		return entry.Pod
+	}
+	return entry.Namespace + "/" + entry.Pod
+}
+
+func (c *cluster) snapshot() Template {
+	countByPod := make(map[string]int64, len(c.stats.countByPod))
+	for k, v := range c.stats.countByPod {
+		countByPod[k] = v
+	}
+
+	return Template{
+		ID:         c.stats.id,
+		Pattern:    strings.Join(c.template, " "),
+		Count:      c.stats.count,
+		CountByPod: countByPod,
+		Examples:   append([]string{}, c.stats.examples...),
+		FirstSeen:  c.stats.firstSeen,
+		LastSeen:   c.stats.lastSeen,
+	}
+}
+
+// observeSpike counts the entry into the spike detection window.
+// It returns true when the template starts spiking in the window.
+func (a *Analyzer) observeSpike(stats *templateStats, at time.Time) bool {
+	if a.spikeWindow <= 0 {
+		return false
+	}
+
+	window := at.Truncate(a.spikeWindow)
+	if stats.window.IsZero() {
+		stats.window = window
+	}
+	if window.After(stats.window) {
+		// close the current window and the empty windows after it
+		const alpha = 0.3
+		elapsed := int(window.Sub(stats.window) / a.spikeWindow)
+		for i := 0; i < elapsed; i++ {
+			var count float64
+			if i == 0 {
+				count = float64(stats.windowCount)
+			}
+			if stats.windows == 0 {
+				stats.baseline = count
+			} else {
+				stats.baseline = alpha*count + (1-alpha)*stats.baseline
+			}
+			stats.windows++
+		}
+		stats.window = window
+		stats.windowCount = 0
+		stats.spiked = false
+	}
+
+	stats.windowCount++
+	if stats.spiked || stats.windows < 1 || stats.windowCount < a.spikeMinCount {
+		return false
+	}
+	if float64(stats.windowCount) < a.spikeFactor*stats.baseline {
+		return false
+	}
+
+	stats.spiked = true
+	return true
+}
+
+// Observe adds the log entry to the templates. It returns the matched template ID
+// and the events raised by the entry. Use Template to get the matched template.
+func (a *Analyzer) Observe(entry podstream.LogEntry) (int, []Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.startedAt.IsZero() {
+		a.startedAt = entry.Time
+	}
+
+	c, isNew := a.drain.add(entry.Log)
+	if isNew {
+		c.stats = &templateStats{
+			id:         len(a.clusters) + 1,
+			countByPod: map[string]int64{},
+			firstSeen:  entry.Time,
+		}
+		a.clusters = append(a.clusters, c)
+	}
+
+	stats := c.stats
+	stats.count++
+	stats.countByPod[podKey(entry)]++
+	if len(stats.examples) < a.maxExamples {
+		stats.examples = append(stats.examples, entry.Log)
+	}
+	if entry.Time.After(stats.lastSeen) {
+		stats.lastSeen = entry.Time
+	}
+	spiked := a.observeSpike(stats, entry.Time)
+
+	var events []Event
+	if isNew && entry.Time.Sub(a.startedAt) >= a.warmup {
+		events = append(events, Event{Kind: EventNewTemplate, Template: c.snapshot(), Entry: entry})
+	}
+	if spiked {
+		events = append(events, Event{
+			Kind:        EventSpike,
+			Template:    c.snapshot(),
+			Entry:       entry,
+			WindowCount: stats.windowCount,
+			Baseline:    stats.baseline,
+		})
+	}
+
+	return stats.id, events
+}
+
+func (a *Analyzer) OnLogs(logs []podstream.LogEntry) {
+	for _, entry := range logs {
+		_, events := a.Observe(entry)
+		if a.onEvent == nil {
+			continue
+		}
+		for _, event := range events {
+			a.onEvent(event)
+		}
+	}
+}
+
+// Template returns the snapshot of the template by ID, or false if not found.
+func (a *Analyzer) Template(id int) (Template, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// IDs are assigned in the order templates are created
+	if id < 1 || id > len(a.clusters) {
+		return Template{}, false
+	}
+	return a.clusters[id-1].snapshot(), true
+}
+
+// Templates returns the snapshot of all templates, ordered by template ID.
+func (a *Analyzer) Templates() []Template {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rv := make([]Template, 0, len(a.clusters))
+	for _, c := range a.clusters {
+		rv = append(rv, c.snapshot())
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].ID < rv[j].ID })
+	return rv
+}
@@ -0,0 +1,134 @@
+package logpattern
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"request", "<*>", "from", "<*>", "took", "<*>", "id=abc"},
+		tokenize("request 42 from 10.0.0.1:8080 took 12.5ms id=abc"),
+	)
+	assert.Equal(
+		t,
+		[]string{"trace", "<*>", "done"},
+		tokenize("trace 3f2a0c9e-1b2d-4c5e-8f90-0a1b2c3d4e5f done"),
+	)
+}
+
+func TestAnalyzer_Templates(t *testing.T) {
+	var events []Event
+	analyzer, err := NewAnalyzer(
+		WithoutSpikeDetection(),
+		WithMaxExamples(2),
+		OnEvent(func(event Event) {
+			events = append(events, event)
+		}),
+	)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	var logs []podstream.LogEntry
+	for i := 0; i < 3; i++ {
+		logs = append(logs,
+			podstream.LogEntry{Time: now, Pod: fmt.Sprintf("pod-%d", i%2), Log: fmt.Sprintf("user u%d logged in", i)},
+			podstream.LogEntry{Time: now, Pod: "pod-0", Log: fmt.Sprintf("connection to 10.0.0.%d refused", i)},
+		)
+	}
+	analyzer.OnLogs(logs)
+
+	templates := analyzer.Templates()
+	if assert.Len(t, templates, 2) {
+		assert.Equal(t, 1, templates[0].ID)
+		assert.Equal(t, "user <*> logged in", templates[0].Pattern)
+		assert.Equal(t, int64(3), templates[0].Count)
+		assert.Equal(t, map[string]int64{"pod-0": 2, "pod-1": 1}, templates[0].CountByPod)
+		assert.Equal(t, []string{"user u0 logged in", "user u1 logged in"}, templates[0].Examples)
+
+		assert.Equal(t, "connection to <*> refused", templates[1].Pattern)
+		assert.Equal(t, int64(3), templates[1].Count)
+	}
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, EventNewTemplate, events[0].Kind)
+		assert.Equal(t, 1, events[0].Template.ID)
+		assert.Equal(t, EventNewTemplate, events[1].Kind)
+		assert.Equal(t, 2, events[1].Template.ID)
+	}
+
+	templateID, newEvents := analyzer.Observe(podstream.LogEntry{Time: now, Log: "user u9 logged in"})
+	assert.Equal(t, 1, templateID)
+	assert.Empty(t, newEvents)
+
+	template, found := analyzer.Template(templateID)
+	assert.True(t, found)
+	assert.Equal(t, int64(4), template.Count)
+	_, found = analyzer.Template(42)
+	assert.False(t, found)
+}
+
+func TestAnalyzer_Warmup(t *testing.T) {
+	var events []Event
+	analyzer, err := NewAnalyzer(
+		WithWarmup(time.Minute),
+		OnEvent(func(event Event) {
+			events = append(events, event)
+		}),
+	)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	analyzer.OnLogs([]podstream.LogEntry{
+		{Time: now, Log: "server started"},
+		{Time: now.Add(2 * time.Minute), Log: "panic: out of memory"},
+	})
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "panic: out of memory", events[0].Entry.Log)
+	}
+}
+
+func TestAnalyzer_Spike(t *testing.T) {
+	var events []Event
+	analyzer, err := NewAnalyzer(
+		WithSpikeDetection(time.Minute, 5, 10),
+		OnEvent(func(event Event) {
+			if event.Kind == EventSpike {
+				events = append(events, event)
+			}
+		}),
+	)
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	// 2 per minute as baseline
+	for minute := 0; minute < 5; minute++ {
+		for i := 0; i < 2; i++ {
+			analyzer.OnLogs([]podstream.LogEntry{{
+				Time: start.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second),
+				Log:  fmt.Sprintf("retrying request %d", i),
+			}})
+		}
+	}
+	assert.Empty(t, events)
+
+	// 30 in the next minute
+	for i := 0; i < 30; i++ {
+		analyzer.OnLogs([]podstream.LogEntry{{
+			Time: start.Add(5*time.Minute + time.Duration(i)*time.Second),
+			Log:  fmt.Sprintf("retrying request %d", i),
+		}})
+	}
+
+	if assert.Len(t, events, 1, "spike should be raised once per window") {
+		assert.Equal(t, int64(10), events[0].WindowCount)
+		assert.InDelta(t, 2, events[0].Baseline, 0.01)
+		assert.Equal(t, "retrying request <*>", events[0].Template.Pattern)
+	}
+}
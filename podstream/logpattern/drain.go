@@ -0,0 +1,145 @@
+package logpattern
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// variablePattern matches tokens which are variables by nature: numbers with optional units,
+// hex strings, UUIDs and IP addresses.
+var variablePattern = regexp.MustCompile(
+	`^(?:` +
+		`[-+]?\d+(?:[.,:/]\d+)*[a-zA-Z%µ]{0,3}` + // numbers, durations, sizes, dates
+		`|(?:0x)?[0-9a-fA-F]{8,}` + // hex strings
+		`|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}` + // uuids
+		`|\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?` + // ipv4 addresses
+		`)$`,
+)
+
+// tokenize splits the log line into tokens and masks variable tokens.
+func tokenize(log string) []string {
+	tokens := strings.Fields(log)
+	for idx, token := range tokens {
+		if variablePattern.MatchString(strings.Trim(token, `,;:()[]{}"'`)) {
+			tokens[idx] = Wildcard
+		}
+	}
+	return tokens
+}
+
+func hasDigit(token string) bool {
+	return strings.IndexAny(token, "0123456789") >= 0
+}
+
+// cluster is a group of log lines sharing a template.
+type cluster struct {
+	template []string
+	stats    *templateStats
+}
+
+// similarity returns the ratio of the tokens equal to the template.
+func (c *cluster) similarity(tokens []string) float64 {
+	if len(tokens) < 1 {
+		return 1
+	}
+
+	var same int
+	for idx, token := range tokens {
+		if c.template[idx] != Wildcard && c.template[idx] == token {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// merge generalizes the template with the tokens.
+func (c *cluster) merge(tokens []string) {
+	for idx, token := range tokens {
+		if c.template[idx] != token {
+			c.template[idx] = Wildcard
+		}
+	}
+}
+
+// node is a node in the parse tree.
+type node struct {
+	children map[string]*node
+	clusters []*cluster
+}
+
+func newNode() *node {
+	return &node{children: map[string]*node{}}
+}
+
+// drain is a fixed depth parse tree for online log clustering.
+// See "Drain: An Online Log Parsing Approach with Fixed Depth Tree" (He et al., ICWS 2017).
+type drain struct {
+	root *node
+
+	// depth is the number of leading tokens used to route lines in the tree.
+	depth int
+	// maxChildren limits the children of a node, the overflows are routed to a wildcard child.
+	maxChildren int
+	// similarityThreshold is the min similarity to join a cluster.
+	similarityThreshold float64
+}
+
+func newDrain(depth int, maxChildren int, similarityThreshold float64) *drain {
+	return &drain{
+		root:                newNode(),
+		depth:               depth,
+		maxChildren:         maxChildren,
+		similarityThreshold: similarityThreshold,
+	}
+}
+
+// leaf returns the leaf node for the tokens.
+func (d *drain) leaf(tokens []string) *node {
+	cur := d.root
+	route := append([]string{strconv.Itoa(len(tokens))}, tokens...)
+	for level := 0; level <= d.depth && level < len(route); level++ {
+		key := route[level]
+		if level > 0 && hasDigit(key) {
+			key = Wildcard
+		}
+
+		next, exists := cur.children[key]
+		if !exists {
+			if level > 0 && len(cur.children) >= d.maxChildren {
+				key = Wildcard
+				next = cur.children[key]
+			}
+			if next == nil {
+				next = newNode()
+				cur.children[key] = next
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+// add adds the log line to the tree. It returns the cluster and tells if the cluster is new.
+func (d *drain) add(log string) (*cluster, bool) {
+	tokens := tokenize(log)
+	leaf := d.leaf(tokens)
+
+	var (
+		best    *cluster
+		bestSim = -1.0
+	)
+	for _, c := range leaf.clusters {
+		if sim := c.similarity(tokens); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	if best != nil && bestSim >= d.similarityThreshold {
+		best.merge(tokens)
+		return best, false
+	}
+
+	c := &cluster{template: tokens}
+	leaf.clusters = append(leaf.clusters, c)
+	return c, true
+}
@@ -0,0 +1,87 @@
+package logpattern
+
+import (
+	"errors"
+	"time"
+)
+
+// WithSimilarityThreshold sets the min ratio of equal tokens for a line to join a template.
+// Defaults to 0.4.
+func WithSimilarityThreshold(threshold float64) Option {
+	return func(analyzer *Analyzer) error {
+		if threshold <= 0 || threshold > 1 {
+			return errors.New("similarity threshold must be in (0, 1]")
+		}
+
+		analyzer.drain.similarityThreshold = threshold
+		return nil
+	}
+}
+
+// WithTreeDepth sets the number of leading tokens used to route lines. Defaults to 4.
+func WithTreeDepth(depth int) Option {
+	return func(analyzer *Analyzer) error {
+		if depth < 1 {
+			return errors.New("tree depth must be positive")
+		}
+
+		analyzer.drain.depth = depth
+		return nil
+	}
+}
+
+// WithMaxExamples sets the max example lines kept per template. Defaults to 3.
+func WithMaxExamples(n int) Option {
+	return func(analyzer *Analyzer) error {
+		if n < 0 {
+			return errors.New("max examples must not be negative")
+		}
+
+		analyzer.maxExamples = n
+		return nil
+	}
+}
+
+// WithWarmup suppresses new template events for templates first seen within
+// the duration since the first log entry.
+func WithWarmup(warmup time.Duration) Option {
+	return func(analyzer *Analyzer) error {
+		analyzer.warmup = warmup
+		return nil
+	}
+}
+
+// WithSpikeDetection configures spike detection. A template spikes when its occurrences
+// in the window reach minCount and factor times the moving average of previous windows.
+// Defaults to 1 minute window, factor 5 and min count 10.
+func WithSpikeDetection(window time.Duration, factor float64, minCount int64) Option {
+	return func(analyzer *Analyzer) error {
+		if window <= 0 {
+			return errors.New("spike window must be positive")
+		}
+		if factor <= 1 {
+			return errors.New("spike factor must be greater than 1")
+		}
+
+		analyzer.spikeWindow = window
+		analyzer.spikeFactor = factor
+		analyzer.spikeMinCount = minCount
+		return nil
+	}
+}
+
+// WithoutSpikeDetection disables spike detection.
+func WithoutSpikeDetection() Option {
+	return func(analyzer *Analyzer) error {
+		analyzer.spikeWindow = 0
+		return nil
+	}
+}
+
+// OnEvent sets the handler to be called with template events.
+func OnEvent(handler EventHandler) Option {
+	return func(analyzer *Analyzer) error {
+		analyzer.onEvent = handler
+		return nil
+	}
+}
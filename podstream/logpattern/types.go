@@ -0,0 +1,58 @@
+package logpattern
+
+import (
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Wildcard is the template token matching any token.
+const Wildcard = "<*>"
+
+// Template is a log message template.
+type Template struct {
+	// ID is the template ID. IDs are assigned in the order templates are created.
+	ID int `json:"id"`
+	// Pattern is the template pattern, variable tokens are replaced with Wildcard.
+	Pattern string `json:"pattern"`
+	// Count is the total occurrences of the template.
+	Count int64 `json:"count"`
+	// CountByPod is the occurrences of the template by pod, keyed by "namespace/pod".
+	CountByPod map[string]int64 `json:"countByPod"`
+	// Examples lists the first example lines of the template.
+	Examples []string `json:"examples"`
+	// FirstSeen is the time of the first occurrence.
+	FirstSeen time.Time `json:"firstSeen"`
+	// LastSeen is the time of the last occurrence.
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// EventKind is the kind of template event.
+type EventKind string
+
+const (
+	// EventNewTemplate is raised when a template shows up for the first time.
+	EventNewTemplate EventKind = "new"
+	// EventSpike is raised when the occurrences of a template spike.
+	EventSpike EventKind = "spike"
+)
+
+// Event is raised when a new or sharply spiking template shows up.
+type Event struct {
+	// Kind is the event kind.
+	Kind EventKind `json:"kind"`
+	// Template is the snapshot of the template at the time of the event.
+	Template Template `json:"template"`
+	// Entry is the log entry triggering the event.
+	Entry podstream.LogEntry `json:"entry"`
+	// WindowCount is the template occurrences in the current window. Only set for EventSpike.
+	WindowCount int64 `json:"windowCount,omitempty"`
+	// Baseline is the average occurrences per window before the spike. Only set for EventSpike.
+	Baseline float64 `json:"baseline,omitempty"`
+}
+
+// EventHandler handles template events.
+type EventHandler func(event Event)
+
+// Option specifies options for configuring the analyzer.
+type Option func(analyzer *Analyzer) error
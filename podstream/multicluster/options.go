@@ -0,0 +1,73 @@
+package multicluster
+
+import (
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// WithLogger sets the logger to be used by the stream.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(s *streamer) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+// WithKubeConfigPath sets the kubeconfig file to load the cluster contexts from.
+// Defaults to the kubeconfig files resolved by kubehelper.LoadRestConfig.
+func WithKubeConfigPath(path string) Option {
+	return func(s *streamer) error {
+		s.kubeConfigPath = path
+		return nil
+	}
+}
+
+// WithStreamOptions sets the options of the podstream.Streamer of each cluster, e.g.
+// the pods selector and the log filter. Logs consumers set here are replaced, use
+// ConsumeLogsWith to consume the merged logs.
+func WithStreamOptions(options ...podstream.Option) Option {
+	return func(s *streamer) error {
+		s.streamOptions = append(s.streamOptions, options...)
+		return nil
+	}
+}
+
+// ConsumeLogsWith sets the consumers of the merged logs.
+func ConsumeLogsWith(first podstream.LogEntryConsumer, other ...podstream.LogEntryConsumer) Option {
+	consumers := append([]podstream.LogEntryConsumer{first}, other...)
+
+	return func(s *streamer) error {
+		s.logsConsumer = podstream.LogEntryConsumers(consumers)
+		return nil
+	}
+}
+
+// OnClusterError sets the handler of the errors of streaming from a cluster, e.g. the
+// cluster is unreachable. The other clusters keep streaming. The handler is called
+// from the streams of multiple clusters concurrently.
+func OnClusterError(handler ErrorHandler) Option {
+	return func(s *streamer) error {
+		if handler == nil {
+			return errors.New("error handler is required")
+		}
+
+		s.onError = handler
+		return nil
+	}
+}
+
+// RetryFailedClusters restarts the stream of failed clusters after the interval until stopped.
+// It should be used with following streams. Defaults to not retry.
+func RetryFailedClusters(interval time.Duration) Option {
+	return func(s *streamer) error {
+		if interval <= 0 {
+			return errors.New("retry interval must be positive")
+		}
+
+		s.retryInterval = interval
+		return nil
+	}
+}
@@ -0,0 +1,321 @@
+package multicluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/kubehelper"
+	"github.com/b4fun/kubekit/podstream"
+	"k8s.io/client-go/kubernetes"
+)
+
+// emitLogsInterval is the interval to emit the merged logs.
+const emitLogsInterval = 1 * time.Second
+
+// clusterIdleTimeout is how long a cluster receiving no logs holds back the merged logs.
+const clusterIdleTimeout = 3 * emitLogsInterval
+
+// Stream streams the pods logs of the namespace from multiple clusters with a
+// podstream.Streamer per cluster. The entries are tagged with the cluster name, and merged
+// in time order before consumed.
+//
+// The merged logs are held back until all the streaming clusters have received newer logs,
+// so a slow cluster, e.g. one still reading a long backlog, does not deliver its logs after
+// newer logs of other clusters. Clusters failed, done or receiving no logs for a while do not
+// hold back the merged logs. The ordering is best-effort for logs arriving later than that, or
+// when the pending logs reach the max pending logs.
+//
+// Failing clusters, e.g. unreachable ones, are reported to the OnClusterError handler while
+// the other clusters keep streaming. It returns an error only if all the clusters fail.
+func Stream(
+	stop <-chan struct{},
+	clusters []Cluster,
+	namespace string,
+	options ...Option,
+) error {
+	if len(clusters) < 1 {
+		return errors.New("at least one cluster is required")
+	}
+
+	s := &streamer{
+		logger:       logger.NoOp,
+		namespace:    namespace,
+		logsConsumer: podstream.LogEntryConsumers{},
+		onError:      func(string, error) {},
+		maxPending:   10000,
+		now:          time.Now,
+		clusters:     map[string]*clusterProgress{},
+	}
+	s.drained = sync.NewCond(&s.mu)
+	for _, opt := range options {
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+	if s.logger == nil {
+		s.logger = logger.NoOp
+	}
+
+	names := map[string]struct{}{}
+	for idx := range clusters {
+		name := clusterName(clusters[idx])
+		if name == "" {
+			return fmt.Errorf("cluster #%d has no name", idx)
+		}
+		if _, exists := names[name]; exists {
+			return fmt.Errorf("duplicated cluster %q", name)
+		}
+		names[name] = struct{}{}
+		s.clusters[name] = &clusterProgress{}
+	}
+
+	return s.start(stop, clusters)
+}
+
+type streamer struct {
+	logger logger.Logger
+
+	namespace      string
+	kubeConfigPath string
+	streamOptions  []podstream.Option
+	logsConsumer   podstream.LogEntryConsumer
+	onError        ErrorHandler
+	retryInterval  time.Duration
+	// maxPending is the max logs held back, OnLogs blocks once reached.
+	maxPending int
+
+	now func() time.Time
+
+	mu sync.Mutex
+	// drained is signaled once the pending logs are emitted
+	drained *sync.Cond
+	// pending holds the logs from the clusters to merge
+	pending []podstream.LogEntry
+	// clusters tracks the progress of each cluster by name
+	clusters map[string]*clusterProgress
+}
+
+// clusterProgress tracks the logs received from a cluster.
+type clusterProgress struct {
+	// active tells if the cluster is streaming.
+	active bool
+	// newest is the time of the newest log received.
+	newest time.Time
+	// lastReceived is when the logs were received last time, or the stream started.
+	lastReceived time.Time
+}
+
+func clusterName(cluster Cluster) string {
+	if cluster.Name != "" {
+		return cluster.Name
+	}
+	return cluster.Context
+}
+
+// OnLogs collects the logs from the cluster streamers. It blocks while the pending logs
+// reach the max pending logs, which slows down the cluster streamers.
+func (s *streamer) OnLogs(logs []podstream.LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) >= s.maxPending {
+		s.drained.Wait()
+	}
+
+	s.pending = append(s.pending, logs...)
+	now := s.now()
+	for _, entry := range logs {
+		progress, exists := s.clusters[entry.Cluster]
+		if !exists {
+			continue
+		}
+		if entry.Time.After(progress.newest) {
+			progress.newest = entry.Time
+		}
+		progress.lastReceived = now
+	}
+}
+
+// setActive marks the cluster streaming or not.
+func (s *streamer) setActive(name string, active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	progress := s.clusters[name]
+	progress.active = active
+	progress.lastReceived = s.now()
+}
+
+// watermark returns the time up to which the streaming clusters have received logs. It returns
+// false if no cluster holds back the logs. It expects the caller holds the mu.
+func (s *streamer) watermark() (time.Time, bool) {
+	now := s.now()
+
+	var (
+		watermark time.Time
+		held      bool
+	)
+	for _, progress := range s.clusters {
+		if !progress.active || now.Sub(progress.lastReceived) > clusterIdleTimeout {
+			continue
+		}
+		if !held || progress.newest.Before(watermark) {
+			watermark = progress.newest
+			held = true
+		}
+	}
+	return watermark, held
+}
+
+// emit emits the pending logs up to the watermark in time order. All pending logs are
+// emitted if flush is set.
+func (s *streamer) emit(flush bool) {
+	s.mu.Lock()
+	sort.SliceStable(s.pending, func(i, j int) bool {
+		return s.pending[i].Time.Before(s.pending[j].Time)
+	})
+	n := len(s.pending)
+	if !flush {
+		if watermark, held := s.watermark(); held {
+			n = sort.Search(len(s.pending), func(i int) bool {
+				return s.pending[i].Time.After(watermark)
+			})
+		}
+		if len(s.pending)-n >= s.maxPending {
+			// too many logs held back, emit the oldest ones to unblock the clusters
+			n = len(s.pending) - s.maxPending/2
+		}
+	}
+	logs := s.pending[:n:n]
+	s.pending = append([]podstream.LogEntry(nil), s.pending[n:]...)
+	s.drained.Broadcast()
+	s.mu.Unlock()
+
+	if len(logs) < 1 {
+		return
+	}
+	s.logsConsumer.OnLogs(logs)
+}
+
+func (s *streamer) start(stop <-chan struct{}, clusters []Cluster) error {
+	var (
+		clusterWorks sync.WaitGroup
+		errsLock     sync.Mutex
+		errs         []error
+	)
+	for _, cluster := range clusters {
+		clusterWorks.Add(1)
+		go func(cluster Cluster) {
+			defer clusterWorks.Done()
+
+			if err := s.streamCluster(stop, cluster); err != nil {
+				errsLock.Lock()
+				errs = append(errs, err)
+				errsLock.Unlock()
+			}
+		}(cluster)
+	}
+
+	clustersDone := make(chan struct{})
+	go func() {
+		defer close(clustersDone)
+		clusterWorks.Wait()
+	}()
+
+	ticker := time.NewTicker(emitLogsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.emit(false)
+		case <-clustersDone:
+			// make sure all collected logs are emitted
+			s.emit(true)
+
+			if len(errs) == len(clusters) {
+				return fmt.Errorf("all %d clusters failed, first error: %w", len(clusters), errs[0])
+			}
+			return nil
+		}
+	}
+}
+
+// streamCluster streams from the cluster until stopped or done. Failures are retried
+// if enabled.
+func (s *streamer) streamCluster(stop <-chan struct{}, cluster Cluster) error {
+	name := clusterName(cluster)
+
+	for {
+		s.setActive(name, true)
+		err := s.runCluster(stop, cluster)
+		// the cluster stops holding back the merged logs
+		s.setActive(name, false)
+		if err == nil {
+			return nil
+		}
+
+		s.logger.Log("failed to stream from cluster %s: %s", name, err)
+		s.onError(name, err)
+		if s.retryInterval <= 0 {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return err
+		case <-time.After(s.retryInterval):
+			s.logger.Log("retrying cluster %s", name)
+		}
+	}
+}
+
+func (s *streamer) runCluster(stop <-chan struct{}, cluster Cluster) error {
+	name := clusterName(cluster)
+
+	client, err := s.clusterClient(cluster)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %w", name, err)
+	}
+
+	options := []podstream.Option{
+		// tag the cluster before other transformers
+		podstream.TransformLogsWith(podstream.LogEntryTransformerFunc(func(logs []podstream.LogEntry) []podstream.LogEntry {
+			for idx := range logs {
+				logs[idx].Cluster = name
+			}
+			return logs
+		})),
+	}
+	options = append(options, s.streamOptions...)
+	options = append(options, podstream.ConsumeLogsWith(s))
+
+	if err := podstream.Stream(stop, client.CoreV1().Pods(s.namespace), options...); err != nil {
+		return fmt.Errorf("cluster %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *streamer) clusterClient(cluster Cluster) (kubernetes.Interface, error) {
+	if cluster.Client != nil {
+		return cluster.Client, nil
+	}
+
+	restConfig := cluster.RestConfig
+	if restConfig == nil {
+		var err error
+		restConfig, err = kubehelper.LoadRestConfig(cluster.Context, s.kubeConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("load rest config: %w", err)
+		}
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,256 @@
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestClient(pods ...string) *fake.Clientset {
+	var objects []runtime.Object
+	for _, pod := range pods {
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      pod,
+				UID:       types.UID(pod),
+				Labels:    map[string]string{"app": "test"},
+			},
+		})
+	}
+	return fake.NewSimpleClientset(objects...)
+}
+
+func newUnreachableClient() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	return client
+}
+
+func TestStream(t *testing.T) {
+	var (
+		logs         []podstream.LogEntry
+		failedLock   sync.Mutex
+		failed       []string
+		kubeConfig   = filepath.Join(t.TempDir(), "missing-kubeconfig")
+		stop         = make(chan struct{})
+		clusterPods  = map[string]string{}
+		consumeCalls int
+	)
+
+	err := Stream(
+		stop,
+		[]Cluster{
+			{Name: "east", Client: newTestClient("east-0", "east-1")},
+			{Name: "west", Client: newTestClient("west-0")},
+			{Name: "down", Client: newUnreachableClient()},
+			{Context: "missing"},
+		},
+		"ns",
+		WithKubeConfigPath(kubeConfig),
+		WithStreamOptions(podstream.FromSelectedPods("app=test")),
+		ConsumeLogsWith(podstream.LogEntryConsumerFunc(func(entries []podstream.LogEntry) {
+			consumeCalls++
+			logs = append(logs, entries...)
+		})),
+		OnClusterError(func(cluster string, err error) {
+			failedLock.Lock()
+			defer failedLock.Unlock()
+
+			failed = append(failed, cluster)
+		}),
+	)
+	assert.NoError(t, err, "unreachable clusters should be tolerated")
+
+	assert.ElementsMatch(t, []string{"down", "missing"}, failed)
+	if assert.Len(t, logs, 3) {
+		for idx, log := range logs {
+			clusterPods[log.Pod] = log.Cluster
+			if idx > 0 {
+				assert.False(t, log.Time.Before(logs[idx-1].Time), "logs should be in time order")
+			}
+		}
+	}
+	assert.Equal(t, map[string]string{"east-0": "east", "east-1": "east", "west-0": "west"}, clusterPods)
+	assert.Positive(t, consumeCalls)
+}
+
+func TestStream_AllFailed(t *testing.T) {
+	err := Stream(
+		make(chan struct{}),
+		[]Cluster{{Name: "down", Client: newUnreachableClient()}},
+		"ns",
+	)
+	assert.Error(t, err)
+
+	err = Stream(make(chan struct{}), nil, "ns")
+	assert.Error(t, err)
+
+	err = Stream(make(chan struct{}), []Cluster{{Client: newTestClient()}}, "ns")
+	assert.Error(t, err, "cluster name is required")
+
+	err = Stream(
+		make(chan struct{}),
+		[]Cluster{{Name: "a", Client: newTestClient()}, {Name: "a", Client: newTestClient()}},
+		"ns",
+	)
+	assert.Error(t, err, "cluster names should be unique")
+}
+
+func TestStream_Retry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var (
+		attemptsLock sync.Mutex
+		attempts     int
+	)
+	client := newTestClient("pod-0")
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attemptsLock.Lock()
+		defer attemptsLock.Unlock()
+
+		attempts++
+		if attempts < 3 {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	var (
+		logsLock sync.Mutex
+		logs     []podstream.LogEntry
+	)
+	done := make(chan error)
+	go func() {
+		done <- Stream(
+			ctx.Done(),
+			[]Cluster{{Name: "flaky", Client: client}},
+			"ns",
+			RetryFailedClusters(10*time.Millisecond),
+			WithStreamOptions(podstream.FollowSelectedPods("app=test")),
+			ConsumeLogsWith(podstream.LogEntryConsumerFunc(func(entries []podstream.LogEntry) {
+				logsLock.Lock()
+				defer logsLock.Unlock()
+
+				logs = append(logs, entries...)
+			})),
+		)
+	}()
+
+	assert.Eventually(t, func() bool {
+		logsLock.Lock()
+		defer logsLock.Unlock()
+
+		return len(logs) == 1 && logs[0].Cluster == "flaky"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+type collectLogs struct {
+	logs []podstream.LogEntry
+}
+
+func (c *collectLogs) OnLogs(logs []podstream.LogEntry) {
+	c.logs = append(c.logs, logs...)
+}
+
+func TestStreamer_Watermark(t *testing.T) {
+	base := time.Now()
+	consumer := &collectLogs{}
+	s := &streamer{
+		logsConsumer: consumer,
+		maxPending:   10,
+		now:          func() time.Time { return base },
+		clusters: map[string]*clusterProgress{
+			"fast": {},
+			"slow": {},
+		},
+	}
+	s.drained = sync.NewCond(&s.mu)
+	s.setActive("fast", true)
+	s.setActive("slow", true)
+
+	entryAt := func(cluster string, offset time.Duration) podstream.LogEntry {
+		return podstream.LogEntry{Cluster: cluster, Time: base.Add(offset)}
+	}
+
+	s.OnLogs([]podstream.LogEntry{entryAt("fast", 1*time.Second), entryAt("fast", 3*time.Second)})
+	s.emit(false)
+	assert.Empty(t, consumer.logs, "slow cluster holds back the logs")
+
+	s.OnLogs([]podstream.LogEntry{entryAt("slow", 2*time.Second)})
+	s.emit(false)
+	if assert.Len(t, consumer.logs, 2) {
+		assert.Equal(t, "fast", consumer.logs[0].Cluster)
+		assert.Equal(t, "slow", consumer.logs[1].Cluster)
+	}
+
+	s.setActive("slow", false)
+	s.emit(false)
+	if assert.Len(t, consumer.logs, 3, "done cluster stops holding back the logs") {
+		assert.Equal(t, base.Add(3*time.Second), consumer.logs[2].Time)
+	}
+
+	s.setActive("slow", true)
+	s.OnLogs([]podstream.LogEntry{entryAt("fast", 4*time.Second)})
+	s.emit(false)
+	assert.Len(t, consumer.logs, 3)
+	s.now = func() time.Time { return base.Add(2 * clusterIdleTimeout) }
+	s.emit(false)
+	assert.Len(t, consumer.logs, 4, "idle cluster stops holding back the logs")
+}
+
+func TestStreamer_MaxPending(t *testing.T) {
+	base := time.Now()
+	consumer := &collectLogs{}
+	s := &streamer{
+		logsConsumer: consumer,
+		maxPending:   4,
+		now:          time.Now,
+		clusters: map[string]*clusterProgress{
+			"fast": {},
+			"slow": {},
+		},
+	}
+	s.drained = sync.NewCond(&s.mu)
+	s.setActive("fast", true)
+	s.setActive("slow", true)
+
+	logs := make([]podstream.LogEntry, 4)
+	for idx := range logs {
+		logs[idx] = podstream.LogEntry{Cluster: "fast", Time: base.Add(time.Duration(idx) * time.Second)}
+	}
+	s.OnLogs(logs)
+
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		s.OnLogs(logs)
+	}()
+	select {
+	case <-received:
+		t.Fatal("OnLogs should block while the pending logs are full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.emit(false)
+	<-received
+	assert.Len(t, consumer.logs, 2, "oldest logs are emitted once the pending logs are full")
+}
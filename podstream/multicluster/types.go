@@ -0,0 +1,25 @@
+package multicluster
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Cluster specifies a cluster to stream from. The client is created from the first set
+// of Client, RestConfig and Context.
+type Cluster struct {
+	// Name is the cluster name tagged to the log entries. Defaults to Context.
+	Name string
+	// Client is the client of the cluster.
+	Client kubernetes.Interface
+	// RestConfig is the rest config to create the client.
+	RestConfig *rest.Config
+	// Context is the kubeconfig context to load the rest config with kubehelper.LoadRestConfig.
+	Context string
+}
+
+// ErrorHandler handles the error of streaming from a cluster.
+type ErrorHandler func(cluster string, err error)
+
+// Option specifies options for configuring the multi-cluster stream.
+type Option func(s *streamer) error
@@ -1,9 +1,16 @@
 package podstream
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/b4fun/kubekit"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedeventsv1 "k8s.io/client-go/kubernetes/typed/events/v1"
 )
 
 // WithLogger sets the logger to be used by the streamer.
@@ -14,11 +21,24 @@ func WithLogger(logger kubekit.Logger) Option {
 	}
 }
 
+// WithHooks sets the hooks observing the streamer internals.
+func WithHooks(hooks Hooks) Option {
+	return func(streamer *Streamer) error {
+		if hooks == nil {
+			return errors.New("hooks is required")
+		}
+
+		streamer.hooks = hooks
+		return nil
+	}
+}
+
 // FromSelectedPods sets the label selector.
-// It stops the streamer after all logs have been consumed.
+// It stops the streamer after all logs have been consumed, overriding an earlier FollowSelectedPods.
 func FromSelectedPods(labelSelector string) Option {
 	return func(streamer *Streamer) error {
 		streamer.labelSelector = labelSelector
+		streamer.follow = false
 		return nil
 	}
 }
@@ -42,6 +62,185 @@ func FromContainer(containerName string) Option {
 	}
 }
 
+// FromPreviousContainer streams the logs of the previous terminated container instances.
+func FromPreviousContainer() Option {
+	return func(streamer *Streamer) error {
+		streamer.podLogOptions.Previous = true
+
+		return nil
+	}
+}
+
+// FromOutputStreams only streams the logs of the output streams, e.g. StreamStderr.
+// It requires a source telling the streams apart, like StreamCRILogs. Streaming from the
+// API server, which merges the streams, fails with this option.
+func FromOutputStreams(first OutputStream, other ...OutputStream) Option {
+	streams := append([]OutputStream{first}, other...)
+
+	return func(streamer *Streamer) error {
+		for _, stream := range streams {
+			if stream != StreamStdout && stream != StreamStderr {
+				return fmt.Errorf("unknown output stream %q", stream)
+			}
+		}
+
+		streamer.outputStreams = streams
+		return nil
+	}
+}
+
+// Since only streams logs newer than the relative duration.
+func Since(d time.Duration) Option {
+	return func(streamer *Streamer) error {
+		if d <= 0 {
+			return errors.New("since duration must be positive")
+		}
+
+		seconds := int64(d.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		streamer.podLogOptions.SinceSeconds = &seconds
+		streamer.podLogOptions.SinceTime = nil
+
+		return nil
+	}
+}
+
+// SinceTime only streams logs after the given time.
+func SinceTime(t time.Time) Option {
+	return func(streamer *Streamer) error {
+		sinceTime := metav1.NewTime(t)
+		streamer.podLogOptions.SinceTime = &sinceTime
+		streamer.podLogOptions.SinceSeconds = nil
+
+		return nil
+	}
+}
+
+// NewestRevisionOnly only streams the pods of the newest workload revision. The revisions are
+// ordered by the pods owners fetched with the apps client, which is the Deployment revision of
+// the ReplicaSet, or the ControllerRevision of the StatefulSet and DaemonSet. Once a newer
+// revision shows up during a rollout or rollback, the pods of older revisions stop being streamed.
+func NewestRevisionOnly(appsClient typedappsv1.AppsV1Interface) Option {
+	return func(streamer *Streamer) error {
+		if appsClient == nil {
+			return errors.New("apps client is required")
+		}
+		streamer.newestRevisionOnly = true
+		streamer.appsClient = appsClient
+		return nil
+	}
+}
+
+// StopFollowingTerminatingPods stops streaming pods once they start terminating,
+// e.g. the old pods being replaced in a rollout.
+func StopFollowingTerminatingPods() Option {
+	return func(streamer *Streamer) error {
+		streamer.stopTerminatingPods = true
+		return nil
+	}
+}
+
+// IncludeEvents emits the core/v1 Events about the tracked pods and their owners
+// as EntryKindEvent entries, interleaved with the logs. Events are not filtered by log filters.
+func IncludeEvents(eventsClient typedcorev1.EventInterface) Option {
+	return func(streamer *Streamer) error {
+		if eventsClient == nil {
+			return errors.New("events client is required")
+		}
+
+		streamer.eventSources = append(streamer.eventSources, coreEventSource{client: eventsClient})
+		return nil
+	}
+}
+
+// IncludeEventsV1 is like IncludeEvents, but reads events.k8s.io/v1 Events.
+func IncludeEventsV1(eventsClient typedeventsv1.EventInterface) Option {
+	return func(streamer *Streamer) error {
+		if eventsClient == nil {
+			return errors.New("events client is required")
+		}
+
+		streamer.eventSources = append(streamer.eventSources, eventsV1Source{client: eventsClient})
+		return nil
+	}
+}
+
+// LimitRate limits the entries and bytes of each pod container log stream with token buckets.
+// Entries beyond the limits are dropped before buffering, so a chatty pod cannot starve others.
+func LimitRate(limit RateLimit) Option {
+	return func(streamer *Streamer) error {
+		if limit.Entries < 0 || limit.Bytes < 0 {
+			return errors.New("rate limits must not be negative")
+		}
+		if limit.EntriesBurst < 0 || limit.BytesBurst < 0 {
+			return errors.New("rate limit bursts must not be negative")
+		}
+
+		streamer.rateLimit = limit
+		return nil
+	}
+}
+
+// SampleAdaptively samples info and lower level entries of each pod container log stream
+// when the volume goes beyond the target entries per second. Warnings and errors are always
+// kept. Sampled entries carry their sample rate in SampleRate.
+func SampleAdaptively(targetPerSecond int) Option {
+	return func(streamer *Streamer) error {
+		if targetPerSecond < 1 {
+			return errors.New("sample target must be positive")
+		}
+
+		streamer.sampleTarget = targetPerSecond
+		return nil
+	}
+}
+
+// ReportStatsEvery reports the log streams statistics to the handler periodically,
+// and once more when the stream stops.
+func ReportStatsEvery(interval time.Duration, handler StatsHandler) Option {
+	return func(streamer *Streamer) error {
+		if interval <= 0 {
+			return errors.New("stats interval must be positive")
+		}
+		if handler == nil {
+			return errors.New("stats handler is required")
+		}
+
+		streamer.statsInterval = interval
+		streamer.statsHandler = handler
+		return nil
+	}
+}
+
+// CheckpointWith persists the position of each pod container log stream to the store
+// after the logs are emitted. Log streams having checkpoints resume from them, overriding
+// the since options, and the lines emitted before are dropped.
+func CheckpointWith(store CheckpointStore) Option {
+	return func(streamer *Streamer) error {
+		if store == nil {
+			return errors.New("checkpoint store is required")
+		}
+
+		streamer.checkpointStore = store
+		return nil
+	}
+}
+
+// CheckpointToFile is like CheckpointWith, but persists the checkpoints to the file.
+func CheckpointToFile(path string) Option {
+	return func(streamer *Streamer) error {
+		store, err := NewFileCheckpointStore(path)
+		if err != nil {
+			return err
+		}
+
+		streamer.checkpointStore = store
+		return nil
+	}
+}
+
 // ConsumeLogsWithFunc sets the log consumer to use.
 func ConsumeLogsWith(first LogEntryConsumer, other ...LogEntryConsumer) Option {
 	consumers := append([]LogEntryConsumer{first}, other...)
@@ -60,6 +259,17 @@ func ConsumeLogsWithFunc(first LogEntryConsumerFunc) Option {
 	}
 }
 
+// TransformLogsWith adds log transformers to run before logs are consumed.
+// Transformers run in the order they are added.
+func TransformLogsWith(first LogEntryTransformer, other ...LogEntryTransformer) Option {
+	transformers := append([]LogEntryTransformer{first}, other...)
+
+	return func(streamer *Streamer) error {
+		streamer.logsTransformers = append(streamer.logsTransformers, transformers...)
+		return nil
+	}
+}
+
 // FilterWithRegex filters the logs with the given regex.
 func FilterWithRegex(expr string) Option {
 	return func(streamer *Streamer) error {
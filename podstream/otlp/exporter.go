@@ -0,0 +1,301 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Resource attribute keys filled from the log stream.
+// See https://opentelemetry.io/docs/reference/specification/resource/semantic_conventions/k8s/ .
+const (
+	AttributeK8SClusterName   = "k8s.cluster.name"
+	AttributeK8SNamespaceName = "k8s.namespace.name"
+	AttributeK8SPodName       = "k8s.pod.name"
+	AttributeK8SContainerName = "k8s.container.name"
+)
+
+// AttributeLogIOStream is the log record attribute key of the container output stream.
+// See https://opentelemetry.io/docs/reference/specification/logs/semantic_conventions/ .
+const AttributeLogIOStream = "log.iostream"
+
+// ScopeName is the instrumentation scope name of the exported records.
+const ScopeName = "github.com/b4fun/kubekit/podstream"
+
+// Exporter is a podstream.LogEntryConsumer that exports log entries as OTLP log records.
+type Exporter struct {
+	logger logger.Logger
+
+	client exportClient
+
+	// httpClient is the client used by HTTP/protobuf export.
+	httpClient *http.Client
+
+	// headers specifies the headers to send with each export request.
+	headers map[string]string
+
+	// timeout specifies the timeout of each export request.
+	timeout time.Duration
+
+	// resourceAttributes specifies the additional resource attributes.
+	resourceAttributes map[string]string
+
+	// traceContext extracts trace context from log lines.
+	traceContext *traceContextExtractor
+
+	// onError is called when export fails.
+	onError func(err error)
+}
+
+var _ podstream.LogEntryConsumer = (*Exporter)(nil)
+
+func newExporter(client exportClient, options ...Option) (*Exporter, error) {
+	exporter := &Exporter{
+		logger:       logger.NoOp,
+		client:       client,
+		httpClient:   http.DefaultClient,
+		timeout:      10 * time.Second,
+		traceContext: newTraceContextExtractor(defaultTraceIDFields, defaultSpanIDFields),
+	}
+	for _, opt := range options {
+		if err := opt(exporter); err != nil {
+			return nil, err
+		}
+	}
+	if exporter.logger == nil {
+		exporter.logger = logger.NoOp
+	}
+
+	return exporter, nil
+}
+
+// NewHTTPExporter creates an exporter sending OTLP over HTTP/protobuf to the given url,
+// for example http://localhost:4318/v1/logs .
+func NewHTTPExporter(url string, options ...Option) (*Exporter, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	client := &httpExportClient{url: url}
+	exporter, err := newExporter(client, options...)
+	if err != nil {
+		return nil, err
+	}
+	client.exporter = exporter
+
+	return exporter, nil
+}
+
+// NewGRPCExporter creates an exporter sending OTLP over gRPC with the given connection.
+// The connection is owned by the caller.
+func NewGRPCExporter(conn grpc.ClientConnInterface, options ...Option) (*Exporter, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("grpc connection is required")
+	}
+
+	client := &grpcExportClient{client: collogspb.NewLogsServiceClient(conn)}
+	exporter, err := newExporter(client, options...)
+	if err != nil {
+		return nil, err
+	}
+	client.exporter = exporter
+
+	return exporter, nil
+}
+
+func (e *Exporter) OnLogs(logs []podstream.LogEntry) {
+	if len(logs) < 1 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	if err := e.client.export(ctx, e.newExportRequest(logs, time.Now())); err != nil {
+		err = fmt.Errorf("export %d log records: %w", len(logs), err)
+		e.logger.Log(err.Error())
+		if e.onError != nil {
+			e.onError(err)
+		}
+	}
+}
+
+type resourceKey struct {
+	cluster   string
+	namespace string
+	pod       string
+	container string
+}
+
+func stringKeyValue(key string, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key: key,
+		Value: &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: value},
+		},
+	}
+}
+
+func (e *Exporter) newResource(key resourceKey) *resourcepb.Resource {
+	var attributes []*commonpb.KeyValue
+	keys := map[string]struct{}{}
+	for _, kv := range []struct{ key, value string }{
+		{AttributeK8SClusterName, key.cluster},
+		{AttributeK8SNamespaceName, key.namespace},
+		{AttributeK8SPodName, key.pod},
+		{AttributeK8SContainerName, key.container},
+	} {
+		if kv.value != "" {
+			attributes = append(attributes, stringKeyValue(kv.key, kv.value))
+			keys[kv.key] = struct{}{}
+		}
+	}
+
+	var extraKeys []string
+	for k := range e.resourceAttributes {
+		if _, exists := keys[k]; exists {
+			// the attributes from the log stream take precedence
+			continue
+		}
+		extraKeys = append(extraKeys, k)
+	}
+	sort.Strings(extraKeys)
+	for _, k := range extraKeys {
+		attributes = append(attributes, stringKeyValue(k, e.resourceAttributes[k]))
+	}
+
+	return &resourcepb.Resource{Attributes: attributes}
+}
+
+var severityNumbers = map[podstream.Level]logspb.SeverityNumber{
+	podstream.LevelTrace: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	podstream.LevelDebug: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	podstream.LevelInfo:  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	podstream.LevelWarn:  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	podstream.LevelError: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	podstream.LevelFatal: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+func (e *Exporter) newLogRecord(entry podstream.LogEntry, observedAt time.Time) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(entry.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(observedAt.UnixNano()),
+		Body: &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: entry.Log},
+		},
+	}
+
+	if level := podstream.ParseLevel(entry.Log); level != podstream.LevelUnknown {
+		record.SeverityNumber = severityNumbers[level]
+		record.SeverityText = strings.ToUpper(level.String())
+	}
+
+	if entry.Stream != "" {
+		record.Attributes = append(record.Attributes, stringKeyValue(AttributeLogIOStream, string(entry.Stream)))
+	}
+
+	record.TraceId, record.SpanId = e.traceContext.extract(entry.Log)
+
+	return record
+}
+
+func (e *Exporter) newExportRequest(
+	logs []podstream.LogEntry,
+	observedAt time.Time,
+) *collogspb.ExportLogsServiceRequest {
+	req := &collogspb.ExportLogsServiceRequest{}
+	scopeLogsByResource := map[resourceKey]*logspb.ScopeLogs{}
+
+	for _, entry := range logs {
+		key := resourceKey{
+			cluster:   entry.Cluster,
+			namespace: entry.Namespace,
+			pod:       entry.Pod,
+			container: entry.Container,
+		}
+
+		scopeLogs, exists := scopeLogsByResource[key]
+		if !exists {
+			scopeLogs = &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{Name: ScopeName},
+			}
+			scopeLogsByResource[key] = scopeLogs
+			req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+				Resource:  e.newResource(key),
+				ScopeLogs: []*logspb.ScopeLogs{scopeLogs},
+			})
+		}
+
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, e.newLogRecord(entry, observedAt))
+	}
+
+	return req
+}
+
+// httpExportClient exports with OTLP/HTTP in binary protobuf encoding.
+type httpExportClient struct {
+	exporter *Exporter
+	url      string
+}
+
+func (c *httpExportClient) export(ctx context.Context, exportReq *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(exportReq)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range c.exporter.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := c.exporter.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	// drain the body to reuse the connection
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return nil
+}
+
+// grpcExportClient exports with OTLP/gRPC.
+type grpcExportClient struct {
+	exporter *Exporter
+	client   collogspb.LogsServiceClient
+}
+
+func (c *grpcExportClient) export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	if len(c.exporter.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(c.exporter.headers))
+	}
+
+	_, err := c.client.Export(ctx, req)
+	return err
+}
@@ -0,0 +1,232 @@
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+var testLogs = []podstream.LogEntry{
+	{
+		Time:      time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC),
+		Log:       `{"level":"error","msg":"failed","trace_id":"` + testTraceID + `","span_id":"` + testSpanID + `"}`,
+		Namespace: "ns",
+		Pod:       "pod-1",
+		Container: "app",
+	},
+	{
+		Time:      time.Date(2022, 5, 1, 10, 0, 1, 0, time.UTC),
+		Log:       `level=info msg="started"`,
+		Namespace: "ns",
+		Pod:       "pod-2",
+		Container: "app",
+		Stream:    podstream.StreamStderr,
+	},
+}
+
+func assertExportRequest(t *testing.T, req *collogspb.ExportLogsServiceRequest) {
+	if !assert.Len(t, req.ResourceLogs, 2) {
+		return
+	}
+
+	attributes := map[string]string{}
+	for _, kv := range req.ResourceLogs[0].Resource.Attributes {
+		attributes[kv.Key] = kv.Value.GetStringValue()
+	}
+	assert.Equal(t, map[string]string{
+		AttributeK8SNamespaceName: "ns",
+		AttributeK8SPodName:       "pod-1",
+		AttributeK8SContainerName: "app",
+		"k8s.cluster.name":        "test",
+	}, attributes)
+
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, uint64(testLogs[0].Time.UnixNano()), record.TimeUnixNano)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, record.SeverityNumber)
+	assert.Equal(t, testLogs[0].Log, record.Body.GetStringValue())
+	assert.Equal(t, testTraceID, hex.EncodeToString(record.TraceId))
+	assert.Equal(t, testSpanID, hex.EncodeToString(record.SpanId))
+	assert.Empty(t, record.Attributes)
+
+	record = req.ResourceLogs[1].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, record.SeverityNumber)
+	assert.Empty(t, record.TraceId)
+	if assert.Len(t, record.Attributes, 1) {
+		assert.Equal(t, AttributeLogIOStream, record.Attributes[0].Key)
+		assert.Equal(t, "stderr", record.Attributes[0].Value.GetStringValue())
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var received *collogspb.ExportLogsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received = &collogspb.ExportLogsServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, received))
+	}))
+	defer server.Close()
+
+	exporter, err := NewHTTPExporter(
+		server.URL+"/v1/logs",
+		WithHeaders(map[string]string{"Authorization": "secret"}),
+		WithResourceAttributes(map[string]string{"k8s.cluster.name": "test"}),
+		OnError(func(err error) {
+			t.Errorf("unexpected error: %s", err)
+		}),
+	)
+	assert.NoError(t, err)
+
+	exporter.OnLogs(testLogs)
+	if assert.NotNil(t, received) {
+		assertExportRequest(t, received)
+	}
+}
+
+func TestHTTPExporter_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var exportErr error
+	exporter, err := NewHTTPExporter(
+		server.URL,
+		OnError(func(err error) {
+			exportErr = err
+		}),
+	)
+	assert.NoError(t, err)
+
+	exporter.OnLogs(testLogs)
+	assert.Error(t, exportErr)
+}
+
+type fakeLogsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	received chan *collogspb.ExportLogsServiceRequest
+	md       chan metadata.MD
+}
+
+func (s *fakeLogsServer) Export(
+	ctx context.Context,
+	req *collogspb.ExportLogsServiceRequest,
+) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.md <- md
+	s.received <- req
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func TestGRPCExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	logsServer := &fakeLogsServer{
+		received: make(chan *collogspb.ExportLogsServiceRequest, 1),
+		md:       make(chan metadata.MD, 1),
+	}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, logsServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	exporter, err := NewGRPCExporter(
+		conn,
+		WithHeaders(map[string]string{"x-tenant": "test"}),
+		WithResourceAttributes(map[string]string{"k8s.cluster.name": "test"}),
+	)
+	assert.NoError(t, err)
+
+	exporter.OnLogs(testLogs)
+
+	assert.Equal(t, []string{"test"}, (<-logsServer.md).Get("x-tenant"))
+	assertExportRequest(t, <-logsServer.received)
+}
+
+func TestExporter_ClusterResource(t *testing.T) {
+	exporter, err := newExporter(nil, WithResourceAttributes(map[string]string{AttributeK8SClusterName: "default"}))
+	assert.NoError(t, err)
+
+	entry := podstream.LogEntry{Time: time.Now(), Log: "hello", Namespace: "ns", Pod: "pod-1", Container: "app"}
+	var logs []podstream.LogEntry
+	for _, cluster := range []string{"", "cluster-1", "cluster-2", "cluster-1"} {
+		entry.Cluster = cluster
+		logs = append(logs, entry)
+	}
+
+	req := exporter.newExportRequest(logs, time.Now())
+	var clusters []string
+	for _, resourceLogs := range req.ResourceLogs {
+		for _, kv := range resourceLogs.Resource.Attributes {
+			if kv.Key == AttributeK8SClusterName {
+				clusters = append(clusters, kv.Value.GetStringValue())
+			}
+		}
+	}
+	assert.Equal(t, []string{"default", "cluster-1", "cluster-2"}, clusters)
+	if assert.Len(t, req.ResourceLogs, 3) {
+		assert.Len(t, req.ResourceLogs[1].ScopeLogs[0].LogRecords, 2)
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	_, err := NewHTTPExporter("http://localhost:4318/v1/logs", WithHTTPClient(nil))
+	assert.Error(t, err)
+}
+
+func TestTraceContextExtractor(t *testing.T) {
+	extractor := newTraceContextExtractor(defaultTraceIDFields, defaultSpanIDFields)
+
+	traceID, spanID := extractor.extract("request done trace_id=" + testTraceID + " span_id=" + testSpanID)
+	assert.Equal(t, testTraceID, hex.EncodeToString(traceID))
+	assert.Equal(t, testSpanID, hex.EncodeToString(spanID))
+
+	traceID, spanID = extractor.extract("traceparent: 00-" + testTraceID + "-" + testSpanID + "-01")
+	assert.Equal(t, testTraceID, hex.EncodeToString(traceID))
+	assert.Equal(t, testSpanID, hex.EncodeToString(spanID))
+
+	traceID, _ = extractor.extract(`{"trace_id":"00000000000000000000000000000000"}`)
+	assert.Nil(t, traceID)
+
+	traceID, _ = extractor.extract(`{"trace_id":"4bf92f3577b34da6"}`)
+	assert.Nil(t, traceID, "trace ids shorter than full length should be ignored")
+	traceID, _ = extractor.extract("request done trace_id=42 span_id=" + testSpanID)
+	assert.Nil(t, traceID, "trace ids shorter than full length should be ignored")
+	traceID, spanID = extractor.extract("request done trace_id=" + testTraceID + " span_id=7")
+	assert.Equal(t, testTraceID, hex.EncodeToString(traceID))
+	assert.Nil(t, spanID, "span ids shorter than full length should be ignored")
+
+	extractor = newTraceContextExtractor([]string{"rid"}, nil)
+	traceID, spanID = extractor.extract(`{"rid":"` + testTraceID + `"}`)
+	assert.Equal(t, testTraceID, hex.EncodeToString(traceID))
+	assert.Nil(t, spanID)
+}
@@ -0,0 +1,84 @@
+package otlp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the exporter.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(exporter *Exporter) error {
+		exporter.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client to use for HTTP/protobuf export.
+// It has no effect on gRPC export.
+func WithHTTPClient(client *http.Client) Option {
+	return func(exporter *Exporter) error {
+		if client == nil {
+			return errors.New("http client is required")
+		}
+		exporter.httpClient = client
+		return nil
+	}
+}
+
+// WithHeaders sets the headers to send with each export request.
+// For gRPC export, the headers are sent as request metadata.
+func WithHeaders(headers map[string]string) Option {
+	return func(exporter *Exporter) error {
+		exporter.headers = headers
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each export request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(exporter *Exporter) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		exporter.timeout = timeout
+		return nil
+	}
+}
+
+// WithResourceAttributes sets additional resource attributes for all exported records,
+// for example "k8s.cluster.name". The attributes filled from the log stream, like the cluster
+// of entries streamed from multiple clusters, take precedence.
+func WithResourceAttributes(attributes map[string]string) Option {
+	return func(exporter *Exporter) error {
+		exporter.resourceAttributes = attributes
+		return nil
+	}
+}
+
+// WithTraceFields sets the log fields to extract trace ID and span ID from.
+// Defaults to "trace_id" / "span_id" and their common variants.
+func WithTraceFields(traceIDField string, spanIDField string) Option {
+	return func(exporter *Exporter) error {
+		if traceIDField == "" {
+			return errors.New("trace ID field is required")
+		}
+
+		exporter.traceContext = newTraceContextExtractor(
+			[]string{traceIDField},
+			[]string{spanIDField},
+		)
+		return nil
+	}
+}
+
+// OnError sets the handler to be called when export fails.
+func OnError(handler func(err error)) Option {
+	return func(exporter *Exporter) error {
+		exporter.onError = handler
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultTraceIDFields = []string{"trace_id", "traceId", "traceID", "trace.id"}
+	defaultSpanIDFields  = []string{"span_id", "spanId", "spanID", "span.id"}
+
+	// traceparentPattern matches W3C traceparent values.
+	traceparentPattern = regexp.MustCompile(`\b00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+)
+
+// traceContextExtractor extracts trace ID and span ID from log lines.
+type traceContextExtractor struct {
+	traceIDFields []string
+	spanIDFields  []string
+
+	// traceIDPattern and spanIDPattern match key-value formatted fields, e.g. trace_id=xxx
+	traceIDPattern *regexp.Regexp
+	spanIDPattern  *regexp.Regexp
+}
+
+func newTraceContextExtractor(traceIDFields []string, spanIDFields []string) *traceContextExtractor {
+	fieldsPattern := func(fields []string, hexLen int) *regexp.Regexp {
+		var quoted []string
+		for _, f := range fields {
+			if f != "" {
+				quoted = append(quoted, regexp.QuoteMeta(f))
+			}
+		}
+		if len(quoted) < 1 {
+			return nil
+		}
+		return regexp.MustCompile(
+			`(?:^|[\s,{])"?(?:` + strings.Join(quoted, "|") + `)"?\s*[=:]\s*"?([0-9a-fA-F]{` +
+				strconv.Itoa(hexLen) + `})\b`,
+		)
+	}
+
+	return &traceContextExtractor{
+		traceIDFields:  traceIDFields,
+		spanIDFields:   spanIDFields,
+		traceIDPattern: fieldsPattern(traceIDFields, 32),
+		spanIDPattern:  fieldsPattern(spanIDFields, 16),
+	}
+}
+
+// decodeID decodes a hex encoded ID of n bytes. It returns nil for invalid or all zero IDs.
+func decodeID(s string, n int) []byte {
+	if len(s) != n*2 {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	if bytes.Equal(id, make([]byte, n)) {
+		return nil
+	}
+	return id
+}
+
+// extract returns the trace ID and span ID found in the log line.
+func (e *traceContextExtractor) extract(log string) (traceID []byte, spanID []byte) {
+	if trimmed := strings.TrimSpace(log); strings.HasPrefix(trimmed, "{") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			lookup := func(keys []string, n int) []byte {
+				for _, key := range keys {
+					if v, ok := fields[key].(string); ok {
+						if id := decodeID(v, n); id != nil {
+							return id
+						}
+					}
+				}
+				return nil
+			}
+
+			traceID = lookup(e.traceIDFields, 16)
+			spanID = lookup(e.spanIDFields, 8)
+			if traceID != nil {
+				return traceID, spanID
+			}
+		}
+	}
+
+	if e.traceIDPattern != nil {
+		if m := e.traceIDPattern.FindStringSubmatch(log); m != nil {
+			traceID = decodeID(m[1], 16)
+		}
+	}
+	if traceID != nil && e.spanIDPattern != nil {
+		if m := e.spanIDPattern.FindStringSubmatch(log); m != nil {
+			spanID = decodeID(m[1], 8)
+		}
+	}
+	if traceID != nil {
+		return traceID, spanID
+	}
+
+	if m := traceparentPattern.FindStringSubmatch(log); m != nil {
+		return decodeID(m[1], 16), decodeID(m[2], 8)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,15 @@
+package otlp
+
+import (
+	"context"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+)
+
+// exportClient sends export requests to the OTLP receiver.
+type exportClient interface {
+	export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error
+}
+
+// Option specifies options for configuring the exporter.
+type Option func(exporter *Exporter) error
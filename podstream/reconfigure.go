@@ -0,0 +1,118 @@
+package podstream
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The methods in this file reconfigure a running streamer without reopening the log streams
+// of the pods being streamed. They are safe to call concurrently with Run.
+
+// SetLogFilter swaps the log filter. A nil filter passes all logs.
+func (s *Streamer) SetLogFilter(filter LogFilter) {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	s.logFilter = filter
+}
+
+// AddConsumer adds a logs consumer, which receives the logs emitted after added.
+// The returned function removes the consumer.
+func (s *Streamer) AddConsumer(consumer LogEntryConsumer) (remove func()) {
+	added := &addedConsumer{consumer: consumer}
+
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	s.addedConsumers = append(s.addedConsumers, added)
+
+	return func() {
+		s.configLock.Lock()
+		defer s.configLock.Unlock()
+
+		for idx, c := range s.addedConsumers {
+			if c == added {
+				s.addedConsumers = append(s.addedConsumers[:idx:idx], s.addedConsumers[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SetLabelSelector changes the pods label selector. When following, the pods no longer
+// matching the selector stop being streamed, and the newly matching pods are picked up.
+func (s *Streamer) SetLabelSelector(labelSelector string) error {
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return fmt.Errorf("parse label selector: %w", err)
+	}
+
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	s.labelSelector = labelSelector
+	if s.selectorChanged != nil {
+		select {
+		case s.selectorChanged <- struct{}{}:
+		default:
+			// a change is pending already
+		}
+	}
+	return nil
+}
+
+// Pause pauses emitting logs. The pods keep being tracked, while reading their logs
+// is held back once the buffer fills up. Held logs are emitted when the stream stops.
+func (s *Streamer) Pause() {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	s.paused = true
+}
+
+// Resume resumes emitting logs paused by Pause.
+func (s *Streamer) Resume() {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	s.paused = false
+}
+
+// Paused tells if emitting logs is paused.
+func (s *Streamer) Paused() bool {
+	s.configLock.RLock()
+	defer s.configLock.RUnlock()
+
+	return s.paused
+}
+
+// addedConsumer is a logs consumer added by AddConsumer.
+type addedConsumer struct {
+	consumer LogEntryConsumer
+}
+
+func (s *Streamer) currentLogFilter() LogFilter {
+	s.configLock.RLock()
+	defer s.configLock.RUnlock()
+
+	return s.logFilter
+}
+
+func (s *Streamer) currentConsumers() LogEntryConsumers {
+	s.configLock.RLock()
+	defer s.configLock.RUnlock()
+
+	consumers := make(LogEntryConsumers, 0, len(s.addedConsumers)+1)
+	consumers = append(consumers, s.logsConsumer)
+	for _, added := range s.addedConsumers {
+		consumers = append(consumers, added.consumer)
+	}
+	return consumers
+}
+
+func (s *Streamer) currentLabelSelector() string {
+	s.configLock.RLock()
+	defer s.configLock.RUnlock()
+
+	return s.labelSelector
+}
@@ -0,0 +1,265 @@
+package podstream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// collectedLogs collects the consumed logs.
+type collectedLogs struct {
+	mu   sync.Mutex
+	logs []LogEntry
+}
+
+func (c *collectedLogs) OnLogs(logs []LogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.logs = append(c.logs, logs...)
+}
+
+func (c *collectedLogs) pods() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pods := map[string]int{}
+	for _, log := range c.logs {
+		pods[log.Pod]++
+	}
+	return pods
+}
+
+func createTestPod(t *testing.T, testCtx *streamerTestCtx, name string, labels map[string]string) {
+	_, err := testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).Create(
+		context.Background(),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testCtx.namespace,
+				Name:      name,
+				UID:       types.UID(name),
+				Labels:    labels,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	assert.NoError(t, err)
+}
+
+func TestStreamer_SetLabelSelector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collected := &collectedLogs{}
+	testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+	testCtx.streamer.follow = true
+	testCtx.streamer.emitLogsInterval = 50 * time.Millisecond
+	createTestPod(t, testCtx, "pod-a", map[string]string{"app": "a"})
+	createTestPod(t, testCtx, "pod-b", map[string]string{"app": "b"})
+	assert.NoError(t, testCtx.streamer.SetLabelSelector("app=a"))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := testCtx.streamer.Run(ctx.Done())
+		assert.NoError(t, err)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return collected.pods()["pod-a"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, collected.pods(), "pod-b")
+
+	assert.Error(t, testCtx.streamer.SetLabelSelector("app in ("))
+	assert.NoError(t, testCtx.streamer.SetLabelSelector("app=b"))
+	assert.Eventually(t, func() bool {
+		return collected.pods()["pod-b"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+	assert.Equal(t, map[string]int{"pod-a": 1, "pod-b": 1}, collected.pods())
+}
+
+// timestampedLogsPods serves the timestamped lines as the logs of each pod.
+type timestampedLogsPods struct {
+	typedcorev1.PodInterface
+
+	lines []string
+}
+
+func (p timestampedLogsPods) GetLogs(name string, opts *corev1.PodLogOptions) *rest.Request {
+	// records the action
+	p.PodInterface.GetLogs(name, opts)
+
+	var logs []string
+	for _, line := range p.lines {
+		at, err := time.Parse(time.RFC3339, strings.SplitN(line, " ", 2)[0])
+		if err != nil || opts.SinceTime == nil || !at.Before(opts.SinceTime.Time) {
+			logs = append(logs, line)
+		}
+	}
+	client := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(strings.Join(logs, "\n"))),
+			}, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         corev1.SchemeGroupVersion,
+		VersionedAPIPath:     "/api/v1/namespaces/test/pods/" + name + "/log",
+	}
+	return client.Request()
+}
+
+func TestStreamer_SetLabelSelector_Resume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collected := &collectedLogs{}
+	testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+	testCtx.streamer.follow = true
+	testCtx.streamer.emitLogsInterval = 10 * time.Millisecond
+	testCtx.streamer.podsClient = timestampedLogsPods{
+		PodInterface: testCtx.streamer.podsClient,
+		lines: []string{
+			"2022-01-01T00:00:00Z first",
+			"2022-01-01T00:00:01Z second",
+			"2022-01-01T00:00:01Z third",
+		},
+	}
+	createTestPod(t, testCtx, "pod-a", map[string]string{"app": "a"})
+	createTestPod(t, testCtx, "pod-b", map[string]string{"app": "b"})
+	assert.NoError(t, testCtx.streamer.SetLabelSelector("app=a"))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := testCtx.streamer.Run(ctx.Done())
+		assert.NoError(t, err)
+	}()
+
+	waitFor := func(expected map[string]int) {
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual(expected, collected.pods())
+		}, 5*time.Second, 10*time.Millisecond, "expected pods: %v, got: %v", expected, collected.pods())
+	}
+	waitFor(map[string]int{"pod-a": 3})
+
+	assert.NoError(t, testCtx.streamer.SetLabelSelector("app=b"))
+	waitFor(map[string]int{"pod-a": 3, "pod-b": 3})
+
+	// the selected again pod resumes after the lines emitted before
+	assert.NoError(t, testCtx.streamer.SetLabelSelector("app in (a, b)"))
+	time.Sleep(100 * time.Millisecond)
+	waitFor(map[string]int{"pod-a": 3, "pod-b": 3})
+
+	var sinceTimes []*metav1.Time
+	for _, action := range testCtx.fakeKubeClient.Actions() {
+		if action.GetSubresource() == "log" {
+			sinceTimes = append(sinceTimes, action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions).SinceTime)
+		}
+	}
+	if assert.Len(t, sinceTimes, 3) {
+		if assert.NotNil(t, sinceTimes[2]) {
+			assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC), sinceTimes[2].Time.UTC())
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestStreamer_Pause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collected := &collectedLogs{}
+	testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+	testCtx.streamer.follow = true
+	testCtx.streamer.emitLogsInterval = 50 * time.Millisecond
+	createTestPod(t, testCtx, "pod-a", testCtx.labels)
+
+	testCtx.streamer.Pause()
+	assert.True(t, testCtx.streamer.Paused())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := testCtx.streamer.Run(ctx.Done())
+		assert.NoError(t, err)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, collected.pods(), "no logs should be emitted while paused")
+
+	testCtx.streamer.Resume()
+	assert.False(t, testCtx.streamer.Paused())
+	assert.Eventually(t, func() bool {
+		return collected.pods()["pod-a"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestStreamer_PauseUntilStopped(t *testing.T) {
+	collected := &collectedLogs{}
+	testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+	createTestPod(t, testCtx, "pod-a", testCtx.labels)
+
+	// held logs are emitted once stopped
+	testCtx.streamer.Pause()
+	err := testCtx.streamer.Run(make(chan struct{}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"pod-a": 1}, collected.pods())
+}
+
+func TestStreamer_SetLogFilter(t *testing.T) {
+	collected := &collectedLogs{}
+	testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+	createTestPod(t, testCtx, "pod-a", testCtx.labels)
+
+	testCtx.streamer.SetLogFilter(LogFilterFunc(func(log string) bool {
+		return false
+	}))
+	assert.NoError(t, testCtx.streamer.Run(make(chan struct{})))
+	assert.Empty(t, collected.pods())
+	assert.Equal(t, 0.0, testCtx.streamer.Stats().Containers[0].MatchRate())
+
+	testCtx.streamer.SetLogFilter(nil)
+	assert.NoError(t, testCtx.streamer.Run(make(chan struct{})))
+	assert.Equal(t, map[string]int{"pod-a": 1}, collected.pods())
+}
+
+func TestStreamer_AddConsumer(t *testing.T) {
+	testCtx := newBaseStreamerTestCtx(t)
+
+	added := &collectedLogs{}
+	remove := testCtx.streamer.AddConsumer(added)
+	testCtx.streamer.emitLogs([]LogEntry{{Time: time.Now(), Pod: "pod-a"}})
+	assert.Equal(t, map[string]int{"pod-a": 1}, added.pods())
+
+	remove()
+	remove()
+	testCtx.streamer.emitLogs([]LogEntry{{Time: time.Now(), Pod: "pod-a"}})
+	assert.Equal(t, map[string]int{"pod-a": 1}, added.pods())
+}
@@ -0,0 +1,182 @@
+package redact
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Built-in detector names.
+const (
+	DetectorBearerToken      = "bearer-token"
+	DetectorJWT              = "jwt"
+	DetectorConnectionString = "connection-string-password"
+	DetectorSecretField      = "secret-field"
+	DetectorAWSAccessKey     = "aws-access-key"
+	DetectorEmail            = "email"
+	DetectorCreditCard       = "credit-card"
+)
+
+// rule redacts the matches of a pattern.
+type rule struct {
+	// name is the rule name used for reporting.
+	name string
+
+	pattern *regexp.Regexp
+
+	// groups lists the capture groups to redact, the first participating group is redacted.
+	// Empty means the whole match.
+	groups []int
+
+	// wrap wraps the masked value of the group, nil means no wrapping.
+	wrap func(group int, masked string) string
+
+	// validate further checks the value to redact, nil means no check.
+	validate func(value string) bool
+}
+
+// newRegexRule creates a rule from the expression. If the expression has a capture group
+// named "secret", only the group is redacted; otherwise the whole match is redacted.
+func newRegexRule(name string, expr string) (*rule, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &rule{name: name, pattern: pattern}
+	if idx := pattern.SubexpIndex("secret"); idx > 0 {
+		r.groups = []int{idx}
+	}
+	return r, nil
+}
+
+// apply redacts the matches in the log line with the masker. It returns the redacted line
+// and the number of redactions.
+func (r *rule) apply(log string, mask Masker) (string, int) {
+	matches := r.pattern.FindAllStringSubmatchIndex(log, -1)
+	if len(matches) < 1 {
+		return log, 0
+	}
+
+	var (
+		b     strings.Builder
+		last  int
+		count int
+	)
+	for _, m := range matches {
+		group, start, end := 0, m[0], m[1]
+		for _, g := range r.groups {
+			group, start, end = g, m[2*g], m[2*g+1]
+			if start >= 0 {
+				break
+			}
+		}
+		if start < 0 || start < last {
+			continue
+		}
+		value := log[start:end]
+		if r.validate != nil && !r.validate(value) {
+			continue
+		}
+
+		masked := mask(value)
+		if r.wrap != nil {
+			masked = r.wrap(group, masked)
+		}
+		b.WriteString(log[last:start])
+		b.WriteString(masked)
+		last = end
+		count++
+	}
+	if count < 1 {
+		return log, 0
+	}
+	b.WriteString(log[last:])
+
+	return b.String(), count
+}
+
+// luhnValid tells if the digits in the value pass the Luhn checksum.
+func luhnValid(value string) bool {
+	var (
+		sum    int
+		digits int
+		double bool
+	)
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// builtinDetectors lists the built-in detectors in the order they apply.
+var builtinDetectors = []*rule{
+	{
+		name:    DetectorBearerToken,
+		pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+		groups:  []int{1},
+	},
+	{
+		name:    DetectorJWT,
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	},
+	{
+		name:    DetectorConnectionString,
+		pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^:/\s@]+:([^@\s/]+)@`),
+		groups:  []int{1},
+	},
+	{
+		name: DetectorSecretField,
+		pattern: regexp.MustCompile(
+			// values starting with "[" or "*" are already masked
+			`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)"?\s*[=:]\s*"?([^\s"&;,\[*][^\s"&;,]*)`,
+		),
+		groups: []int{1},
+	},
+	{
+		name:    DetectorAWSAccessKey,
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	{
+		name:    DetectorEmail,
+		pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	{
+		name:     DetectorCreditCard,
+		pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		validate: luhnValid,
+	},
+}
+
+// jsonFieldRule creates a rule redacting the string or number value of the JSON field.
+// Field names are matched case-insensitively at any nesting level.
+func jsonFieldRule(field string) *rule {
+	return &rule{
+		name: "json-field:" + field,
+		pattern: regexp.MustCompile(
+			`(?i)"` + regexp.QuoteMeta(field) + `"\s*:\s*(?:"((?:[^"\\]|\\.)*)"|(-?\d[\d.eE+-]*))`,
+		),
+		groups: []int{1, 2},
+		wrap: func(group int, masked string) string {
+			if group == 2 {
+				// quote masked numbers to keep the line valid JSON
+				return strconv.Quote(masked)
+			}
+			return masked
+		},
+	}
+}
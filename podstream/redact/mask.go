@@ -0,0 +1,40 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// FullMask replaces the whole value with "[REDACTED]".
+func FullMask() Masker {
+	return func(value string) string {
+		return "[REDACTED]"
+	}
+}
+
+// PartialMask keeps the first keepPrefix and the last keepSuffix characters of the value
+// and masks the rest with "*". Values too short to keep both ends are fully masked.
+func PartialMask(keepPrefix int, keepSuffix int) Masker {
+	return func(value string) string {
+		runes := []rune(value)
+		if keepPrefix+keepSuffix >= len(runes) {
+			return strings.Repeat("*", len(runes))
+		}
+
+		masked := strings.Repeat("*", len(runes)-keepPrefix-keepSuffix)
+		return string(runes[:keepPrefix]) + masked + string(runes[len(runes)-keepSuffix:])
+	}
+}
+
+// HashMask replaces the value with a keyed hash, so equal values can still be correlated
+// without revealing them.
+func HashMask(key []byte) Masker {
+	return func(value string) string {
+		mac := hmac.New(sha256.New, key)
+		io.WriteString(mac, value)
+		return "[sha256:" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+	}
+}
@@ -0,0 +1,84 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithDetectors enables only the named built-in detectors.
+// Calling it without names disables all built-in detectors.
+func WithDetectors(names ...string) Option {
+	return func(redactor *Redactor) error {
+		var detectors []*rule
+		for _, name := range names {
+			var found bool
+			for _, d := range builtinDetectors {
+				if d.name == name {
+					detectors = append(detectors, d)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("unknown detector: %q", name)
+			}
+		}
+
+		redactor.detectors = detectors
+		return nil
+	}
+}
+
+// WithRule adds a user regex rule.
+// If the expression has a capture group named "secret", only the group is redacted,
+// for example `session=(?P<secret>\w+)`. Otherwise the whole match is redacted.
+func WithRule(name string, expr string) Option {
+	return func(redactor *Redactor) error {
+		if name == "" {
+			return errors.New("rule name is required")
+		}
+
+		r, err := newRegexRule(name, expr)
+		if err != nil {
+			return fmt.Errorf("compile rule %q: %w", name, err)
+		}
+
+		redactor.userRules = append(redactor.userRules, r)
+		return nil
+	}
+}
+
+// WithJSONFields redacts the values of the named JSON fields.
+func WithJSONFields(fields ...string) Option {
+	return func(redactor *Redactor) error {
+		for _, field := range fields {
+			if field == "" {
+				return errors.New("field name is required")
+			}
+			redactor.jsonFieldRules = append(redactor.jsonFieldRules, jsonFieldRule(field))
+		}
+
+		return nil
+	}
+}
+
+// WithMasker sets the masker for redacted values. Defaults to FullMask.
+func WithMasker(mask Masker) Option {
+	return func(redactor *Redactor) error {
+		if mask == nil {
+			return errors.New("masker is required")
+		}
+
+		redactor.mask = mask
+		return nil
+	}
+}
+
+// OnRedacted sets the handler to be called with the redaction counts of each batch.
+// The handler is not called for batches without redaction.
+func OnRedacted(handler func(counts map[string]int)) Option {
+	return func(redactor *Redactor) error {
+		redactor.onRedacted = handler
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package redact
+
+import (
+	"sync"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Redactor is a podstream.LogEntryTransformer that redacts secrets and PII in log lines.
+//
+// Rules apply in this order: JSON fields, built-in detectors, then user rules.
+type Redactor struct {
+	// jsonFieldRules, detectors and userRules list the rules to apply.
+	jsonFieldRules []*rule
+	detectors      []*rule
+	userRules      []*rule
+
+	// mask masks the redacted values.
+	mask Masker
+
+	// onRedacted is called with the redaction counts of each batch.
+	onRedacted func(counts map[string]int)
+
+	countsLock sync.Mutex
+	counts     map[string]int64
+}
+
+var _ podstream.LogEntryTransformer = (*Redactor)(nil)
+
+// New creates a redactor. All built-in detectors are enabled by default.
+func New(options ...Option) (*Redactor, error) {
+	redactor := &Redactor{
+		detectors: builtinDetectors,
+		mask:      FullMask(),
+		counts:    map[string]int64{},
+	}
+	for _, opt := range options {
+		if err := opt(redactor); err != nil {
+			return nil, err
+		}
+	}
+
+	return redactor, nil
+}
+
+// Redact redacts the log line. It returns the redacted line and the redaction counts by rule name.
+func (r *Redactor) Redact(log string) (string, map[string]int) {
+	var counts map[string]int
+
+	for _, rules := range [][]*rule{r.jsonFieldRules, r.detectors, r.userRules} {
+		for _, rule := range rules {
+			var n int
+			log, n = rule.apply(log, r.mask)
+			if n > 0 {
+				if counts == nil {
+					counts = map[string]int{}
+				}
+				counts[rule.name] += n
+			}
+		}
+	}
+
+	return log, counts
+}
+
+func (r *Redactor) TransformLogs(logs []podstream.LogEntry) []podstream.LogEntry {
+	var batchCounts map[string]int
+	for idx := range logs {
+		var counts map[string]int
+		logs[idx].Log, counts = r.Redact(logs[idx].Log)
+		for name, n := range counts {
+			if batchCounts == nil {
+				batchCounts = map[string]int{}
+			}
+			batchCounts[name] += n
+		}
+	}
+
+	if len(batchCounts) > 0 {
+		r.countsLock.Lock()
+		for name, n := range batchCounts {
+			r.counts[name] += int64(n)
+		}
+		r.countsLock.Unlock()
+
+		if r.onRedacted != nil {
+			r.onRedacted(batchCounts)
+		}
+	}
+
+	return logs
+}
+
+// Counts returns the total redaction counts by rule name.
+func (r *Redactor) Counts() map[string]int64 {
+	r.countsLock.Lock()
+	defer r.countsLock.Unlock()
+
+	rv := make(map[string]int64, len(r.counts))
+	for name, n := range r.counts {
+		rv[name] = n
+	}
+	return rv
+}
@@ -0,0 +1,7 @@
+package redact
+
+// Masker masks a sensitive value.
+type Masker func(value string) string
+
+// Option specifies options for configuring the redactor.
+type Option func(redactor *Redactor) error
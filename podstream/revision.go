@@ -0,0 +1,110 @@
+package podstream
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// trackedPod is a pod being streamed.
+type trackedPod struct {
+	// revision is the workload revision of the pod.
+	revision string
+	// labels is the labels of the pod.
+	labels map[string]string
+	// cancel stops streaming the pod.
+	cancel func()
+	// done is closed once the pod stream stops.
+	done chan struct{}
+	// last is the position of the lines read from the pod, set once done.
+	last Checkpoint
+}
+
+// podRevision returns the workload revision of the pod, or empty if not found.
+func podRevision(pod *corev1.Pod) string {
+	if hash, exists := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; exists {
+		return hash
+	}
+	if hash, exists := pod.Labels[appsv1.ControllerRevisionHashLabelKey]; exists {
+		return hash
+	}
+	return ""
+}
+
+// revisionAnnotation is the annotation of the Deployment revision number on the ReplicaSets.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// newestRevision tracks the newest workload revision, ordered by the revision numbers of
+// the pods owners.
+type newestRevision struct {
+	newest string
+	// number is the revision number of the newest revision.
+	number int64
+	// observed is set once any pod is observed.
+	observed bool
+	// resolve returns the revision number of the pod.
+	resolve func(pod *corev1.Pod) int64
+}
+
+// observe adds the pod of the revision, and returns true when the newest revision changes.
+func (r *newestRevision) observe(pod *corev1.Pod, revision string) bool {
+	if r.observed && r.newest == revision {
+		return false
+	}
+
+	number := r.resolve(pod)
+	if r.observed && number <= r.number {
+		return false
+	}
+	r.observed = true
+	r.newest = revision
+	r.number = number
+	return true
+}
+
+// revisionNumber returns the revision number of the pod from its owner, which is the
+// Deployment revision of the ReplicaSet, or the ControllerRevision of the StatefulSet and
+// DaemonSet. It returns 0 if not resolved.
+func (s *Streamer) revisionNumber(ctx context.Context, pod *corev1.Pod) int64 {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return 0
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		replicaSet, err := s.appsClient.ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			s.logger.Log("failed to get ReplicaSet %s: %s", owner.Name, err)
+			return 0
+		}
+		number, err := strconv.ParseInt(replicaSet.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			s.logger.Log("failed to parse revision of ReplicaSet %s: %s", owner.Name, err)
+			return 0
+		}
+		return number
+	case "StatefulSet", "DaemonSet":
+		hash := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+		if hash == "" {
+			return 0
+		}
+		// the StatefulSet pods are labeled with the revision name, the DaemonSet pods with the hash
+		name := hash
+		if !strings.HasPrefix(hash, owner.Name+"-") {
+			name = owner.Name + "-" + hash
+		}
+		controllerRevision, err := s.appsClient.ControllerRevisions(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			s.logger.Log("failed to get ControllerRevision %s: %s", name, err)
+			return 0
+		}
+		return controllerRevision.Revision
+	default:
+		return 0
+	}
+}
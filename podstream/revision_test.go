@@ -0,0 +1,262 @@
+package podstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodRevision(t *testing.T) {
+	pod := &corev1.Pod{}
+	assert.Empty(t, podRevision(pod))
+
+	pod.Labels = map[string]string{appsv1.ControllerRevisionHashLabelKey: "web-5d8f"}
+	assert.Equal(t, "web-5d8f", podRevision(pod))
+
+	pod.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "7c9b"}
+	assert.Equal(t, "7c9b", podRevision(pod))
+}
+
+func TestNewestRevision(t *testing.T) {
+	numbers := map[string]int64{"a": 1, "b": 2}
+	podOf := func(revision string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"rev": revision}}}
+	}
+
+	r := newestRevision{
+		resolve: func(pod *corev1.Pod) int64 {
+			return numbers[pod.Labels["rev"]]
+		},
+	}
+	assert.True(t, r.observe(podOf("a"), "a"))
+	assert.False(t, r.observe(podOf("a"), "a"))
+	assert.True(t, r.observe(podOf("b"), "b"))
+	assert.False(t, r.observe(podOf("a"), "a"), "older revision should not change the newest revision")
+	assert.Equal(t, "b", r.newest)
+
+	// rolled back to revision a
+	numbers["a"] = 3
+	assert.True(t, r.observe(podOf("a"), "a"))
+	assert.Equal(t, "a", r.newest)
+}
+
+func TestStreamer_RevisionNumber(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "test",
+				Name:        "web-7c9b",
+				Annotations: map[string]string{revisionAnnotation: "4"},
+			},
+		},
+		&appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "db-5d8f"},
+			Revision:   2,
+		},
+		&appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "agent-6f7d"},
+			Revision:   3,
+		},
+	)
+	s := &Streamer{logger: logger.NoOp, appsClient: client.AppsV1()}
+
+	podOf := func(kind string, name string, labels map[string]string) *corev1.Pod {
+		controller := true
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "test",
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: kind, Name: name, Controller: &controller},
+				},
+			},
+		}
+	}
+
+	assert.Equal(t, int64(0), s.revisionNumber(ctx, &corev1.Pod{}))
+	assert.Equal(t, int64(4), s.revisionNumber(ctx, podOf("ReplicaSet", "web-7c9b", nil)))
+	assert.Equal(t, int64(0), s.revisionNumber(ctx, podOf("ReplicaSet", "missing", nil)))
+	assert.Equal(t, int64(2), s.revisionNumber(ctx, podOf("StatefulSet", "db", map[string]string{
+		appsv1.ControllerRevisionHashLabelKey: "db-5d8f",
+	})))
+	assert.Equal(t, int64(3), s.revisionNumber(ctx, podOf("DaemonSet", "agent", map[string]string{
+		appsv1.ControllerRevisionHashLabelKey: "6f7d",
+	})))
+}
+
+func TestStreamer_Revision(t *testing.T) {
+	now := time.Now()
+	newPod := func(name string, hash string, created time.Time) *corev1.Pod {
+		controller := true
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				UID:               types.UID(name),
+				CreationTimestamp: metav1.NewTime(created),
+				Labels: map[string]string{
+					"app":                                  "test",
+					appsv1.DefaultDeploymentUniqueLabelKey: hash,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "web-" + hash, Controller: &controller},
+				},
+			},
+		}
+	}
+	// setRevision sets the Deployment revision number of the ReplicaSet of the hash
+	setRevision := func(t *testing.T, testCtx *streamerTestCtx, hash string, number string) {
+		replicaSets := testCtx.fakeKubeClient.AppsV1().ReplicaSets(testCtx.namespace)
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testCtx.namespace,
+				Name:        "web-" + hash,
+				Annotations: map[string]string{revisionAnnotation: number},
+			},
+		}
+		if _, err := replicaSets.Update(context.Background(), replicaSet, metav1.UpdateOptions{}); err == nil {
+			return
+		}
+		_, err := replicaSets.Create(context.Background(), replicaSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	t.Run("tag revision", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWithFunc(func(logs []LogEntry) {
+			loadedLogs = append(loadedLogs, logs...)
+		}))
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, newPod("pod-old", "old", now), metav1.CreateOptions{})
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, newPod("pod-new", "new", now.Add(time.Minute)), metav1.CreateOptions{})
+
+		assert.NoError(t, testCtx.streamer.start(ctx.Done()))
+
+		revisions := map[string]string{}
+		for _, entry := range loadedLogs {
+			revisions[entry.Pod] = entry.Revision
+		}
+		assert.Equal(t, map[string]string{"pod-old": "old", "pod-new": "new"}, revisions)
+	})
+
+	t.Run("newest revision only", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newBaseStreamerTestCtx(
+			t,
+			ConsumeLogsWithFunc(func(logs []LogEntry) {
+				loadedLogs = append(loadedLogs, logs...)
+			}),
+		)
+		testCtx.streamer.newestRevisionOnly = true
+		testCtx.streamer.appsClient = testCtx.fakeKubeClient.AppsV1()
+		setRevision(t, testCtx, "old", "1")
+		setRevision(t, testCtx, "new", "2")
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, newPod("pod-new", "new", now.Add(time.Minute)), metav1.CreateOptions{})
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, newPod("pod-old", "old", now), metav1.CreateOptions{})
+
+		assert.NoError(t, testCtx.streamer.start(ctx.Done()))
+
+		if assert.NotEmpty(t, loadedLogs) {
+			for _, entry := range loadedLogs {
+				assert.Equal(t, "pod-new", entry.Pod)
+			}
+		}
+	})
+
+	t.Run("newest revision flips back", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		collected := &collectedLogs{}
+		testCtx := newBaseStreamerTestCtx(t, ConsumeLogsWith(collected))
+		testCtx.streamer.newestRevisionOnly = true
+		testCtx.streamer.appsClient = testCtx.fakeKubeClient.AppsV1()
+		testCtx.streamer.follow = true
+		setRevision(t, testCtx, "a", "1")
+		setRevision(t, testCtx, "b", "2")
+		testCtx.streamer.emitLogsInterval = 10 * time.Millisecond
+		podsClient := testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace)
+		podA1 := newPod("pod-a1", "a", now)
+		_, err := podsClient.Create(ctx, podA1, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, testCtx.streamer.start(ctx.Done()))
+		}()
+		waitFor := func(expected map[string]int) {
+			assert.Eventually(t, func() bool {
+				return assert.ObjectsAreEqual(expected, collected.pods())
+			}, 5*time.Second, 10*time.Millisecond, "expected pods: %v, got: %v", expected, collected.pods())
+		}
+		waitFor(map[string]int{"pod-a1": 1})
+
+		_, err = podsClient.Create(ctx, newPod("pod-b", "b", now.Add(time.Minute)), metav1.CreateOptions{})
+		assert.NoError(t, err)
+		waitFor(map[string]int{"pod-a1": 1, "pod-b": 1})
+
+		// rolled back to revision a, even the new pod is created in the same second
+		setRevision(t, testCtx, "a", "3")
+		_, err = podsClient.Create(ctx, newPod("pod-a2", "a", now.Add(time.Minute)), metav1.CreateOptions{})
+		assert.NoError(t, err)
+		waitFor(map[string]int{"pod-a1": 1, "pod-b": 1, "pod-a2": 1})
+
+		// the pod of revision a streams again once observed
+		podA1.Annotations = map[string]string{"updated": "true"}
+		_, err = podsClient.Update(ctx, podA1, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+		waitFor(map[string]int{"pod-a1": 2, "pod-b": 1, "pod-a2": 1})
+
+		cancel()
+		wg.Wait()
+	})
+
+	t.Run("terminating pods", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newBaseStreamerTestCtx(
+			t,
+			StopFollowingTerminatingPods(),
+			ConsumeLogsWithFunc(func(logs []LogEntry) {
+				loadedLogs = append(loadedLogs, logs...)
+			}),
+		)
+		terminating := newPod("pod-old", "old", now)
+		deletedAt := metav1.NewTime(now)
+		terminating.DeletionTimestamp = &deletedAt
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, terminating, metav1.CreateOptions{})
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, newPod("pod-new", "new", now), metav1.CreateOptions{})
+
+		assert.NoError(t, testCtx.streamer.start(ctx.Done()))
+
+		if assert.NotEmpty(t, loadedLogs) {
+			for _, entry := range loadedLogs {
+				assert.Equal(t, "pod-new", entry.Pod)
+			}
+		}
+	})
+}
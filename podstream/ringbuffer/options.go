@@ -0,0 +1,57 @@
+package ringbuffer
+
+import (
+	"errors"
+	"time"
+)
+
+// WithMaxEntries sets the max entries kept per pod container. Defaults to 1000.
+func WithMaxEntries(n int) Option {
+	return func(store *Store) error {
+		if n < 1 {
+			return errors.New("max entries must be positive")
+		}
+
+		store.maxEntries = n
+		return nil
+	}
+}
+
+// WithMaxBytes sets the max log bytes kept per pod container. Defaults to no limit.
+func WithMaxBytes(n int) Option {
+	return func(store *Store) error {
+		if n < 0 {
+			return errors.New("max bytes must not be negative")
+		}
+
+		store.maxBytes = n
+		return nil
+	}
+}
+
+// WithMaxIdle sets how long the entries of a pod container are kept after its last write,
+// e.g. after the pod is gone. Defaults to 1 hour, zero keeps them until evicted by
+// WithMaxContainers.
+func WithMaxIdle(d time.Duration) Option {
+	return func(store *Store) error {
+		if d < 0 {
+			return errors.New("max idle must not be negative")
+		}
+
+		store.maxIdle = d
+		return nil
+	}
+}
+
+// WithMaxContainers sets the max pod containers kept, the least recently written ones
+// are evicted first. Defaults to no limit.
+func WithMaxContainers(n int) Option {
+	return func(store *Store) error {
+		if n < 0 {
+			return errors.New("max containers must not be negative")
+		}
+
+		store.maxContainers = n
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package ringbuffer
+
+import (
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// ring is a bounded ring of log entries, oldest entries are overwritten first.
+type ring struct {
+	entries []podstream.LogEntry
+	head    int
+	size    int
+	bytes   int
+	// lastWrite is the time of the last push.
+	lastWrite time.Time
+}
+
+func newRing(capacity int) *ring {
+	return &ring{entries: make([]podstream.LogEntry, capacity)}
+}
+
+func (r *ring) at(idx int) podstream.LogEntry {
+	return r.entries[(r.head+idx)%len(r.entries)]
+}
+
+func (r *ring) pop() {
+	r.bytes -= len(r.entries[r.head].Log)
+	r.entries[r.head] = podstream.LogEntry{}
+	r.head = (r.head + 1) % len(r.entries)
+	r.size--
+}
+
+// push adds the entry, evicting the oldest entries to fit in the max bytes.
+func (r *ring) push(entry podstream.LogEntry, maxBytes int) {
+	if r.size == len(r.entries) {
+		r.pop()
+	}
+	for maxBytes > 0 && r.size > 0 && r.bytes+len(entry.Log) > maxBytes {
+		r.pop()
+	}
+
+	r.entries[(r.head+r.size)%len(r.entries)] = entry
+	r.size++
+	r.bytes += len(entry.Log)
+}
+
+// last returns the last n entries in write order.
+func (r *ring) last(n int) []podstream.LogEntry {
+	if n > r.size || n < 0 {
+		n = r.size
+	}
+	rv := make([]podstream.LogEntry, 0, n)
+	for idx := r.size - n; idx < r.size; idx++ {
+		rv = append(rv, r.at(idx))
+	}
+	return rv
+}
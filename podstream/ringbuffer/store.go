@@ -0,0 +1,198 @@
+package ringbuffer
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Store is a podstream.LogEntryConsumer keeping the latest entries of each pod container
+// in bounded ring buffers. It is safe for concurrent readers while logs are written.
+type Store struct {
+	now func() time.Time
+
+	// maxEntries is the max entries kept per pod container.
+	maxEntries int
+	// maxBytes is the max log bytes kept per pod container. Zero means no limit.
+	maxBytes int
+	// maxIdle is how long the entries of a pod container are kept after its last write.
+	// Zero means no limit.
+	maxIdle time.Duration
+	// maxContainers is the max pod containers kept. Zero means no limit.
+	maxContainers int
+
+	mu               sync.RWMutex
+	rings            map[string]*ring
+	subscribers      map[int]Handler
+	lastSubscriberID int
+}
+
+var _ podstream.LogEntryConsumer = (*Store)(nil)
+
+// New creates a ring buffer store.
+func New(options ...Option) (*Store, error) {
+	store := &Store{
+		now:         time.Now,
+		maxEntries:  1000,
+		maxIdle:     time.Hour,
+		rings:       map[string]*ring{},
+		subscribers: map[int]Handler{},
+	}
+	for _, opt := range options {
+		if err := opt(store); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+func containerKey(entry podstream.LogEntry) string {
+	return entry.Namespace + "/" + entry.Pod + "/" + entry.Container
+}
+
+// OnLogs writes the logs to the store, then notifies the subscribers.
+func (store *Store) OnLogs(logs []podstream.LogEntry) {
+	now := store.now()
+
+	store.mu.Lock()
+	for _, entry := range logs {
+		key := containerKey(entry)
+		r, exists := store.rings[key]
+		if !exists {
+			r = newRing(store.maxEntries)
+			store.rings[key] = r
+		}
+		r.push(entry, store.maxBytes)
+		r.lastWrite = now
+	}
+	store.evict(now)
+
+	ids := make([]int, 0, len(store.subscribers))
+	for id := range store.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	handlers := make([]Handler, 0, len(ids))
+	for _, id := range ids {
+		handlers = append(handlers, store.subscribers[id])
+	}
+	store.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(logs)
+	}
+}
+
+// evict removes the rings idle for too long, then the least recently written ones beyond
+// the max containers.
+func (store *Store) evict(now time.Time) {
+	if store.maxIdle > 0 {
+		for key, r := range store.rings {
+			if now.Sub(r.lastWrite) > store.maxIdle {
+				delete(store.rings, key)
+			}
+		}
+	}
+
+	if store.maxContainers < 1 || len(store.rings) <= store.maxContainers {
+		return
+	}
+	keys := make([]string, 0, len(store.rings))
+	for key := range store.rings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return store.rings[keys[i]].lastWrite.Before(store.rings[keys[j]].lastWrite)
+	})
+	for _, key := range keys[:len(keys)-store.maxContainers] {
+		delete(store.rings, key)
+	}
+}
+
+func sortByTime(logs []podstream.LogEntry) []podstream.LogEntry {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Time.Before(logs[j].Time)
+	})
+	return logs
+}
+
+// matchPod tells if the entry is from the pod, which is either the pod name
+// or in the form of "namespace/name".
+func matchPod(entry podstream.LogEntry, pod string) bool {
+	return entry.Pod == pod || entry.Namespace+"/"+entry.Pod == pod
+}
+
+// Tail returns the last n entries of the pod across its containers in time order.
+// The pod is either the pod name or in the form of "namespace/name".
+// No entries are returned if n is not positive.
+func (store *Store) Tail(pod string, n int) []podstream.LogEntry {
+	if n < 1 {
+		return nil
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var rv []podstream.LogEntry
+	for _, r := range store.rings {
+		if r.size < 1 || !matchPod(r.at(0), pod) {
+			continue
+		}
+		rv = append(rv, r.last(n)...)
+	}
+
+	rv = sortByTime(rv)
+	if len(rv) > n {
+		rv = rv[len(rv)-n:]
+	}
+	return rv
+}
+
+// Search returns the entries within the window before now matching the filter in time order.
+// A nil filter matches all entries, a zero window searches all entries.
+func (store *Store) Search(filter podstream.LogFilter, window time.Duration) []podstream.LogEntry {
+	var since time.Time
+	if window > 0 {
+		since = store.now().Add(-window)
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var rv []podstream.LogEntry
+	for _, r := range store.rings {
+		for idx := 0; idx < r.size; idx++ {
+			entry := r.at(idx)
+			if entry.Time.Before(since) {
+				continue
+			}
+			if filter != nil && !filter.FilterLog(entry.Log) {
+				continue
+			}
+			rv = append(rv, entry)
+		}
+	}
+
+	return sortByTime(rv)
+}
+
+// Subscribe calls the handler with the logs written to the store after subscribing.
+// The returned function stops the subscription.
+func (store *Store) Subscribe(handler Handler) (unsubscribe func()) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lastSubscriberID++
+	id := store.lastSubscriberID
+	store.subscribers[id] = handler
+
+	return func() {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		delete(store.subscribers, id)
+	}
+}
@@ -0,0 +1,157 @@
+package ringbuffer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func logsOf(entries []podstream.LogEntry) []string {
+	var rv []string
+	for _, entry := range entries {
+		rv = append(rv, entry.Log)
+	}
+	return rv
+}
+
+func TestStore_Tail(t *testing.T) {
+	store, err := New(WithMaxEntries(3))
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		store.OnLogs([]podstream.LogEntry{
+			{Time: start.Add(time.Duration(2*i) * time.Second), Namespace: "ns", Pod: "web", Container: "app", Log: fmt.Sprintf("app %d", i)},
+			{Time: start.Add(time.Duration(2*i+1) * time.Second), Namespace: "ns", Pod: "web", Container: "proxy", Log: fmt.Sprintf("proxy %d", i)},
+			{Time: start, Namespace: "ns", Pod: "db", Log: fmt.Sprintf("db %d", i)},
+		})
+	}
+
+	assert.Equal(t, []string{"proxy 3", "app 4", "proxy 4"}, logsOf(store.Tail("web", 3)))
+	assert.Equal(t, []string{"app 2", "proxy 2", "app 3", "proxy 3", "app 4", "proxy 4"}, logsOf(store.Tail("ns/web", 10)))
+	assert.Equal(t, []string{"db 4"}, logsOf(store.Tail("db", 1)))
+	assert.Empty(t, store.Tail("missing", 1))
+	assert.Empty(t, store.Tail("web", 0))
+	assert.Empty(t, store.Tail("web", -1))
+}
+
+func TestStore_Eviction(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("max idle", func(t *testing.T) {
+		store, err := New(WithMaxIdle(time.Minute))
+		assert.NoError(t, err)
+		store.now = func() time.Time { return now }
+
+		store.OnLogs([]podstream.LogEntry{{Time: now, Pod: "gone", Log: "bye"}})
+		now = now.Add(30 * time.Second)
+		store.OnLogs([]podstream.LogEntry{{Time: now, Pod: "web", Log: "hello"}})
+		assert.Len(t, store.Tail("gone", 1), 1)
+
+		now = now.Add(time.Minute)
+		store.OnLogs([]podstream.LogEntry{{Time: now, Pod: "web", Log: "hello again"}})
+		assert.Empty(t, store.Tail("gone", 1), "idle containers should be evicted")
+		assert.Len(t, store.Tail("web", 10), 2)
+	})
+
+	t.Run("max containers", func(t *testing.T) {
+		store, err := New(WithMaxIdle(0), WithMaxContainers(2))
+		assert.NoError(t, err)
+		store.now = func() time.Time { return now }
+
+		for _, pod := range []string{"a", "b", "c"} {
+			now = now.Add(time.Second)
+			store.OnLogs([]podstream.LogEntry{{Time: now, Pod: pod, Log: pod}})
+		}
+		assert.Empty(t, store.Tail("a", 1), "least recently written container should be evicted")
+		assert.Len(t, store.Tail("b", 1), 1)
+		assert.Len(t, store.Tail("c", 1), 1)
+	})
+
+	_, err := New(WithMaxIdle(-time.Second))
+	assert.Error(t, err)
+	_, err = New(WithMaxContainers(-1))
+	assert.Error(t, err)
+}
+
+func TestStore_MaxBytes(t *testing.T) {
+	store, err := New(WithMaxBytes(10))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	store.OnLogs([]podstream.LogEntry{
+		{Time: now, Pod: "web", Log: "aaaa"},
+		{Time: now, Pod: "web", Log: "bbbb"},
+		{Time: now, Pod: "web", Log: "cccc"},
+	})
+	assert.Equal(t, []string{"bbbb", "cccc"}, logsOf(store.Tail("web", 10)))
+
+	store.OnLogs([]podstream.LogEntry{{Time: now, Pod: "web", Log: strings.Repeat("d", 20)}})
+	assert.Len(t, store.Tail("web", 10), 1, "oversized entry should still be kept")
+}
+
+func TestStore_Search(t *testing.T) {
+	store, err := New()
+	assert.NoError(t, err)
+
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.OnLogs([]podstream.LogEntry{
+		{Time: now.Add(-2 * time.Minute), Pod: "a", Log: "level=error old"},
+		{Time: now.Add(-30 * time.Second), Pod: "b", Log: "level=error recent"},
+		{Time: now.Add(-20 * time.Second), Pod: "a", Log: "level=info recent"},
+	})
+
+	errorsOnly := podstream.LogFilterFunc(func(log string) bool {
+		return strings.Contains(log, "error")
+	})
+	assert.Equal(t, []string{"level=error recent"}, logsOf(store.Search(errorsOnly, time.Minute)))
+	assert.Equal(t, []string{"level=error old", "level=error recent"}, logsOf(store.Search(errorsOnly, 0)))
+	assert.Len(t, store.Search(nil, time.Minute), 2)
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	store, err := New()
+	assert.NoError(t, err)
+
+	var received []string
+	unsubscribe := store.Subscribe(func(logs []podstream.LogEntry) {
+		received = append(received, logsOf(logs)...)
+	})
+
+	store.OnLogs([]podstream.LogEntry{{Time: time.Now(), Pod: "a", Log: "foo"}})
+	unsubscribe()
+	store.OnLogs([]podstream.LogEntry{{Time: time.Now(), Pod: "a", Log: "bar"}})
+
+	assert.Equal(t, []string{"foo"}, received)
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	store, err := New(WithMaxEntries(10))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			store.OnLogs([]podstream.LogEntry{{Time: time.Now(), Pod: "a", Log: "foo"}})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			store.Tail("a", 5)
+			store.Search(nil, time.Minute)
+		}
+	}()
+	wg.Wait()
+
+	assert.Len(t, store.Tail("a", 100), 10)
+}
@@ -0,0 +1,11 @@
+package ringbuffer
+
+import (
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Handler handles log entries written to the store.
+type Handler func(logs []podstream.LogEntry)
+
+// Option specifies options for configuring the store.
+type Option func(store *Store) error
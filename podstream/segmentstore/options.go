@@ -0,0 +1,54 @@
+package segmentstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the store.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(store *Store) error {
+		store.logger = logger
+		return nil
+	}
+}
+
+// WithSegmentSize sets the size in bytes to roll the active segment at. Defaults to 16MiB.
+func WithSegmentSize(size int64) Option {
+	return func(store *Store) error {
+		if size < 1 {
+			return errors.New("segment size must be positive")
+		}
+
+		store.segmentSize = size
+		return nil
+	}
+}
+
+// WithMaxBytes sets the max total size in bytes of the segments, oldest segments are
+// removed first. Defaults to 1GiB. Zero means no limit.
+func WithMaxBytes(size int64) Option {
+	return func(store *Store) error {
+		if size < 0 {
+			return errors.New("max bytes must not be negative")
+		}
+
+		store.maxBytes = size
+		return nil
+	}
+}
+
+// WithMaxAge removes the segments whose entries are all older than the age.
+// Defaults to 7 days. Zero means no limit.
+func WithMaxAge(age time.Duration) Option {
+	return func(store *Store) error {
+		if age < 0 {
+			return errors.New("max age must not be negative")
+		}
+
+		store.maxAge = age
+		return nil
+	}
+}
@@ -0,0 +1,294 @@
+package segmentstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+const segmentFileExt = ".seg"
+
+func isTokenSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// tokenize splits the log into lower case tokens for indexing.
+func tokenize(log string) []string {
+	return uniqueTokens(strings.FieldsFunc(strings.ToLower(log), isTokenSeparator))
+}
+
+// queryTokens splits the text to search into lower case tokens for looking up the index.
+// Only the complete tokens are returned, the first and last ones are dropped unless bounded by
+// separators, as they may be parts of longer tokens in the logs, e.g. "time" of "timeout".
+func queryTokens(text string) []string {
+	text = strings.ToLower(text)
+	fields := strings.FieldsFunc(text, isTokenSeparator)
+	if r, _ := utf8.DecodeRuneInString(text); len(fields) > 0 && !isTokenSeparator(r) {
+		fields = fields[1:]
+	}
+	if r, _ := utf8.DecodeLastRuneInString(text); len(fields) > 0 && !isTokenSeparator(r) {
+		fields = fields[:len(fields)-1]
+	}
+	return uniqueTokens(fields)
+}
+
+// uniqueTokens filters out the duplicated and single character fields.
+func uniqueTokens(fields []string) []string {
+	seen := make(map[string]struct{}, len(fields))
+	tokens := fields[:0]
+	for _, field := range fields {
+		if len(field) < 2 {
+			continue
+		}
+		if _, exists := seen[field]; exists {
+			continue
+		}
+		seen[field] = struct{}{}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// segment is an append-only file of JSON encoded log entries, indexed in memory.
+type segment struct {
+	id   uint64
+	path string
+	file *os.File
+	size int64
+
+	minTime time.Time
+	maxTime time.Time
+
+	// offsets, times and levels are indexed by entry ordinal.
+	offsets []int64
+	times   []int64
+	levels  []podstream.Level
+
+	namespaces map[string][]uint32
+	pods       map[string][]uint32
+	containers map[string][]uint32
+	tokens     map[string][]uint32
+}
+
+func segmentPath(dir string, id uint64) string {
+	return fmt.Sprintf("%s/%020d%s", dir, id, segmentFileExt)
+}
+
+func newSegment(id uint64, path string, file *os.File) *segment {
+	return &segment{
+		id:         id,
+		path:       path,
+		file:       file,
+		namespaces: map[string][]uint32{},
+		pods:       map[string][]uint32{},
+		containers: map[string][]uint32{},
+		tokens:     map[string][]uint32{},
+	}
+}
+
+// openSegment opens the segment file and rebuilds its index. A partially written
+// entry at the end of the file is truncated.
+func openSegment(dir string, id uint64) (*segment, error) {
+	path := segmentPath(dir, id)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open segment %s: %w", path, err)
+	}
+
+	s := newSegment(id, path, file)
+	reader := bufio.NewReader(io.NewSectionReader(file, 0, 1<<62))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("read segment %s: %w", path, err)
+		}
+
+		var entry podstream.LogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			break
+		}
+		s.index(entry, int64(len(line)))
+	}
+
+	if err := file.Truncate(s.size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("truncate segment %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// index adds the entry written at the end of the segment to the index.
+func (s *segment) index(entry podstream.LogEntry, n int64) {
+	ordinal := uint32(len(s.offsets))
+	s.offsets = append(s.offsets, s.size)
+	s.times = append(s.times, entry.Time.UnixNano())
+	s.levels = append(s.levels, podstream.ParseLevel(entry.Log))
+	s.size += n
+
+	if s.minTime.IsZero() || entry.Time.Before(s.minTime) {
+		s.minTime = entry.Time
+	}
+	if entry.Time.After(s.maxTime) {
+		s.maxTime = entry.Time
+	}
+
+	s.namespaces[entry.Namespace] = append(s.namespaces[entry.Namespace], ordinal)
+	s.pods[entry.Pod] = append(s.pods[entry.Pod], ordinal)
+	s.containers[entry.Container] = append(s.containers[entry.Container], ordinal)
+	for _, token := range tokenize(entry.Log) {
+		s.tokens[token] = append(s.tokens[token], ordinal)
+	}
+}
+
+// append writes the entries to the segment.
+func (s *segment) append(logs []podstream.LogEntry) error {
+	var buf bytes.Buffer
+	lengths := make([]int64, 0, len(logs))
+	for _, entry := range logs {
+		start := buf.Len()
+		if err := json.NewEncoder(&buf).Encode(entry); err != nil {
+			return fmt.Errorf("encode entry: %w", err)
+		}
+		lengths = append(lengths, int64(buf.Len()-start))
+	}
+
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		// drop the partially written entries, so the segment stays decodable
+		if truncateErr := s.file.Truncate(s.size); truncateErr != nil {
+			return fmt.Errorf("write segment %s: %w (truncate: %s)", s.path, err, truncateErr)
+		}
+		return fmt.Errorf("write segment %s: %w", s.path, err)
+	}
+
+	for idx, entry := range logs {
+		s.index(entry, lengths[idx])
+	}
+	return nil
+}
+
+func (s *segment) read(ordinal uint32) (podstream.LogEntry, error) {
+	end := s.size
+	if int(ordinal)+1 < len(s.offsets) {
+		end = s.offsets[ordinal+1]
+	}
+	b := make([]byte, end-s.offsets[ordinal])
+	if _, err := s.file.ReadAt(b, s.offsets[ordinal]); err != nil {
+		return podstream.LogEntry{}, fmt.Errorf("read segment %s: %w", s.path, err)
+	}
+
+	var entry podstream.LogEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return podstream.LogEntry{}, fmt.Errorf("decode entry in segment %s: %w", s.path, err)
+	}
+	return entry, nil
+}
+
+// intersect returns the ordinals in both sorted lists.
+func intersect(a []uint32, b []uint32) []uint32 {
+	var rv []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			rv = append(rv, a[i])
+			i++
+			j++
+		}
+	}
+	return rv
+}
+
+// candidates returns the ordinals of entries matching the indexed conditions of the query.
+func (s *segment) candidates(q Query) []uint32 {
+	var (
+		lists    [][]uint32
+		postings = func(index map[string][]uint32, value string) {
+			lists = append(lists, index[value])
+		}
+	)
+	if q.Namespace != "" {
+		postings(s.namespaces, q.Namespace)
+	}
+	if q.Pod != "" {
+		postings(s.pods, q.Pod)
+	}
+	if q.Container != "" {
+		postings(s.containers, q.Container)
+	}
+	// the index only narrows down the candidates, the text is matched when reading the entries
+	for _, token := range queryTokens(q.Contains) {
+		postings(s.tokens, token)
+	}
+
+	if len(lists) < 1 {
+		rv := make([]uint32, len(s.offsets))
+		for idx := range rv {
+			rv[idx] = uint32(idx)
+		}
+		return rv
+	}
+
+	// intersect from the shortest list
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	rv := lists[0]
+	for _, list := range lists[1:] {
+		if len(rv) < 1 {
+			break
+		}
+		rv = intersect(rv, list)
+	}
+	return rv
+}
+
+// query returns the entries matching the query.
+func (s *segment) query(q Query) ([]podstream.LogEntry, error) {
+	if !q.Since.IsZero() && s.maxTime.Before(q.Since) {
+		return nil, nil
+	}
+	if !q.Until.IsZero() && !s.minTime.Before(q.Until) {
+		return nil, nil
+	}
+
+	contains := strings.ToLower(q.Contains)
+	var rv []podstream.LogEntry
+	for _, ordinal := range s.candidates(q) {
+		t := s.times[ordinal]
+		if !q.Since.IsZero() && t < q.Since.UnixNano() {
+			continue
+		}
+		if !q.Until.IsZero() && t >= q.Until.UnixNano() {
+			continue
+		}
+		if s.levels[ordinal] < q.MinLevel {
+			continue
+		}
+
+		entry, err := s.read(ordinal)
+		if err != nil {
+			return nil, err
+		}
+		if contains != "" && !strings.Contains(strings.ToLower(entry.Log), contains) {
+			continue
+		}
+		rv = append(rv, entry)
+	}
+	return rv, nil
+}
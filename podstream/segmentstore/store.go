@@ -0,0 +1,236 @@
+package segmentstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Store is a podstream.LogEntryConsumer writing log entries into append-only segment files
+// in a local directory. Entries are indexed by time, namespace, pod, container, level and
+// tokens, so they can be queried after the pods are gone.
+//
+// The index is kept in memory and rebuilt from the segment files when the store is opened.
+type Store struct {
+	logger logger.Logger
+	now    func() time.Time
+
+	dir string
+
+	// segmentSize is the size to roll the active segment at.
+	segmentSize int64
+	// maxBytes is the max total size of the segments. Zero means no limit.
+	maxBytes int64
+	// maxAge is the max age of the segments. Zero means no limit.
+	maxAge time.Duration
+
+	mu       sync.RWMutex
+	segments []*segment
+	closed   bool
+}
+
+var _ podstream.LogEntryConsumer = (*Store)(nil)
+
+func listSegmentIDs(dir string) ([]uint64, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || filepath.Ext(name) != segmentFileExt {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// Open opens the store in the directory. The directory is created if not exists.
+func Open(dir string, options ...Option) (*Store, error) {
+	store := &Store{
+		logger:      logger.NoOp,
+		now:         time.Now,
+		dir:         dir,
+		segmentSize: 16 << 20,
+		maxBytes:    1 << 30,
+		maxAge:      7 * 24 * time.Hour,
+	}
+	for _, opt := range options {
+		if err := opt(store); err != nil {
+			return nil, err
+		}
+	}
+	if store.logger == nil {
+		store.logger = logger.NoOp
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+	ids, err := listSegmentIDs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+	if len(ids) < 1 {
+		ids = []uint64{1}
+	}
+	for _, id := range ids {
+		s, err := openSegment(dir, id)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store.segments = append(store.segments, s)
+	}
+
+	if err := store.enforceRetention(); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *Store) activeSegment() *segment {
+	return store.segments[len(store.segments)-1]
+}
+
+// roll starts a new active segment.
+func (store *Store) roll() error {
+	s, err := openSegment(store.dir, store.activeSegment().id+1)
+	if err != nil {
+		return err
+	}
+	store.segments = append(store.segments, s)
+	return nil
+}
+
+func (store *Store) removeOldestSegment() error {
+	s := store.segments[0]
+	store.segments = store.segments[1:]
+
+	s.file.Close()
+	if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("remove segment %s: %w", s.path, err)
+	}
+	store.logger.Log("removed segment %s", s.path)
+	return nil
+}
+
+// enforceRetention removes the oldest segments beyond the retention.
+// The active segment is never removed.
+func (store *Store) enforceRetention() error {
+	if store.maxAge > 0 {
+		expiredBefore := store.now().Add(-store.maxAge)
+		for len(store.segments) > 1 && store.segments[0].maxTime.Before(expiredBefore) {
+			if err := store.removeOldestSegment(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if store.maxBytes > 0 {
+		var total int64
+		for _, s := range store.segments {
+			total += s.size
+		}
+		for len(store.segments) > 1 && total > store.maxBytes {
+			total -= store.segments[0].size
+			if err := store.removeOldestSegment(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Write writes the logs to the store.
+func (store *Store) Write(logs []podstream.LogEntry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.closed {
+		return errors.New("store is closed")
+	}
+
+	if err := store.activeSegment().append(logs); err != nil {
+		return err
+	}
+	if store.activeSegment().size >= store.segmentSize {
+		if err := store.roll(); err != nil {
+			return err
+		}
+	}
+	return store.enforceRetention()
+}
+
+// OnLogs writes the logs to the store. Write errors are logged.
+func (store *Store) OnLogs(logs []podstream.LogEntry) {
+	if err := store.Write(logs); err != nil {
+		store.logger.Log("failed to write logs: %s", err)
+	}
+}
+
+// Query returns the entries matching the query in time order.
+func (store *Store) Query(q Query) ([]podstream.LogEntry, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if store.closed {
+		return nil, errors.New("store is closed")
+	}
+
+	var rv []podstream.LogEntry
+	for _, s := range store.segments {
+		logs, err := s.query(q)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, logs...)
+	}
+
+	sort.SliceStable(rv, func(i, j int) bool {
+		return rv[i].Time.Before(rv[j].Time)
+	})
+	if q.Limit > 0 && len(rv) > q.Limit {
+		rv = rv[:q.Limit]
+	}
+	return rv, nil
+}
+
+// Close closes the store.
+func (store *Store) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.closed {
+		return nil
+	}
+	store.closed = true
+
+	var rv error
+	for _, s := range store.segments {
+		if err := s.file.Close(); err != nil && rv == nil {
+			rv = err
+		}
+	}
+	return rv
+}
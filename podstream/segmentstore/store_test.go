@@ -0,0 +1,155 @@
+package segmentstore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func logsOf(entries []podstream.LogEntry) []string {
+	var rv []string
+	for _, entry := range entries {
+		rv = append(rv, entry.Log)
+	}
+	return rv
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"level", "error", "msg", "db", "timeout", "after", "30s"},
+		tokenize(`level=error msg="DB timeout after 30s" a=DB`),
+	)
+}
+
+func TestQueryTokens(t *testing.T) {
+	assert.Equal(t, []string{"timeout"}, queryTokens("upstream timeout after"))
+	assert.Equal(t, []string{"upstream", "timeout"}, queryTokens(`"upstream timeout"`))
+	assert.Empty(t, queryTokens("time"))
+	assert.Empty(t, queryTokens("upstream time"))
+}
+
+func TestStore_Query(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, WithSegmentSize(512), WithMaxAge(0))
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	for i := 0; i < 10; i++ {
+		store.OnLogs([]podstream.LogEntry{
+			{Time: at(i), Namespace: "prod", Pod: "api-0", Container: "app", Log: fmt.Sprintf("level=info msg=handled request %d", i)},
+			{Time: at(i), Namespace: "prod", Pod: "api-0", Container: "app", Log: fmt.Sprintf("level=error msg=\"upstream Timeout\" attempt=%d", i)},
+			{Time: at(i), Namespace: "dev", Pod: "api-0", Container: "app", Log: fmt.Sprintf("level=error msg=\"upstream timeout\" attempt=%d", i)},
+		})
+	}
+	assert.Greater(t, len(store.segments), 1, "segments should be rolled")
+
+	query := Query{
+		Namespace: "prod",
+		MinLevel:  podstream.LevelError,
+		Since:     at(2),
+		Until:     at(5),
+		Contains:  "upstream timeout",
+	}
+	logs, err := store.Query(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`level=error msg="upstream Timeout" attempt=2`,
+		`level=error msg="upstream Timeout" attempt=3`,
+		`level=error msg="upstream Timeout" attempt=4`,
+	}, logsOf(logs))
+
+	logs, err = store.Query(Query{Pod: "api-0", Contains: "request", Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"level=info msg=handled request 0", "level=info msg=handled request 1"}, logsOf(logs))
+
+	logs, err = store.Query(Query{Namespace: "dev", Contains: "stream time", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`level=error msg="upstream timeout" attempt=0`}, logsOf(logs), "partial tokens should match")
+
+	logs, err = store.Query(Query{Container: "missing"})
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+
+	assert.NoError(t, store.Close())
+
+	// reopen after pods are gone
+	store, err = Open(dir, WithMaxAge(0))
+	assert.NoError(t, err)
+	defer store.Close()
+
+	logs, err = store.Query(query)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 3)
+}
+
+func TestStore_PartialWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.NoError(t, store.Write([]podstream.LogEntry{{Time: now, Pod: "a", Log: "first"}}))
+	assert.NoError(t, store.Close())
+
+	// simulate a crash in the middle of writing an entry
+	f, err := os.OpenFile(segmentPath(dir, 1), os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2022-05-01T10:00:00Z","lo`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = Open(dir)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.NoError(t, store.Write([]podstream.LogEntry{{Time: now, Pod: "a", Log: "second"}}))
+	logs, err := store.Query(Query{Pod: "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, logsOf(logs))
+}
+
+func TestStore_Retention(t *testing.T) {
+	now := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("max age", func(t *testing.T) {
+		store, err := Open(t.TempDir(), WithSegmentSize(1), WithMaxAge(24*time.Hour))
+		assert.NoError(t, err)
+		defer store.Close()
+		store.now = func() time.Time { return now }
+
+		store.OnLogs([]podstream.LogEntry{{Time: now.Add(-48 * time.Hour), Pod: "a", Log: "old"}})
+		store.OnLogs([]podstream.LogEntry{{Time: now.Add(-1 * time.Hour), Pod: "a", Log: "new"}})
+
+		logs, err := store.Query(Query{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"new"}, logsOf(logs))
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := Open(dir, WithSegmentSize(1), WithMaxBytes(200), WithMaxAge(0))
+		assert.NoError(t, err)
+		defer store.Close()
+
+		for i := 0; i < 10; i++ {
+			store.OnLogs([]podstream.LogEntry{{Time: now, Pod: "a", Log: fmt.Sprintf("entry %d", i)}})
+		}
+
+		logs, err := store.Query(Query{})
+		assert.NoError(t, err)
+		assert.Less(t, len(logs), 10)
+		assert.Equal(t, "entry 9", logs[len(logs)-1].Log)
+
+		ids, err := listSegmentIDs(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, len(store.segments), len(ids), "removed segments should be deleted")
+	})
+}
@@ -0,0 +1,30 @@
+package segmentstore
+
+import (
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Query specifies the conditions of log entries to query. Empty fields match all entries.
+type Query struct {
+	// Namespace matches the namespace of the entries.
+	Namespace string
+	// Pod matches the pod name of the entries.
+	Pod string
+	// Container matches the container name of the entries.
+	Container string
+	// MinLevel matches entries at or above the level.
+	MinLevel podstream.Level
+	// Since matches entries at or after the time.
+	Since time.Time
+	// Until matches entries before the time.
+	Until time.Time
+	// Contains matches entries containing the text, case insensitively.
+	Contains string
+	// Limit limits the number of entries returned, earliest entries first. Zero means no limit.
+	Limit int
+}
+
+// Option specifies options for configuring the store.
+type Option func(store *Store) error
@@ -0,0 +1,213 @@
+package podstream
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ContainerStats is the statistics of a pod container log stream.
+type ContainerStats struct {
+	// Namespace is the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Pod is the pod name.
+	Pod string `json:"pod"`
+	// Container is the container name. It is empty when the container cannot be determined.
+	Container string `json:"container,omitempty"`
+	// Lines is the number of lines read.
+	Lines int64 `json:"lines"`
+	// Bytes is the number of log bytes read.
+	Bytes int64 `json:"bytes"`
+	// Matched is the number of lines passing the log filter.
+	Matched int64 `json:"matched"`
+	// Dropped is the number of matched lines dropped by rate limits or sampling.
+	Dropped int64 `json:"dropped"`
+	// Levels is the number of lines by parsed level name.
+	Levels map[string]int64 `json:"levels"`
+	// FirstTime is the time of the first line.
+	FirstTime time.Time `json:"firstTime"`
+	// LastTime is the time of the last line.
+	LastTime time.Time `json:"lastTime"`
+	// Reconnects is the number of times the log stream is reopened after broken.
+	Reconnects int64 `json:"reconnects"`
+}
+
+// MatchRate returns the ratio of lines passing the log filter.
+func (c ContainerStats) MatchRate() float64 {
+	if c.Lines < 1 {
+		return 0
+	}
+	return float64(c.Matched) / float64(c.Lines)
+}
+
+// Stats is a snapshot of the streamer statistics.
+type Stats struct {
+	// Containers lists the statistics of each pod container, sorted by bytes descending.
+	Containers []ContainerStats `json:"containers"`
+	// Lines is the total number of lines read.
+	Lines int64 `json:"lines"`
+	// Bytes is the total number of log bytes read.
+	Bytes int64 `json:"bytes"`
+}
+
+// TopTalkers returns the n pod containers emitting the most bytes.
+func (s Stats) TopTalkers(n int) []ContainerStats {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(s.Containers) {
+		n = len(s.Containers)
+	}
+	return s.Containers[:n]
+}
+
+// StatsHandler handles statistics snapshots.
+type StatsHandler func(stats Stats)
+
+// containerStats collects the statistics of a pod container log stream.
+type containerStats struct {
+	mu    sync.Mutex
+	stats ContainerStats
+}
+
+func (c *containerStats) observeLine(log string, at time.Time, matched bool) {
+	level := ParseLevel(log)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Lines++
+	c.stats.Bytes += int64(len(log))
+	c.stats.Levels[level.String()]++
+	if matched {
+		c.stats.Matched++
+	}
+	if c.stats.FirstTime.IsZero() || at.Before(c.stats.FirstTime) {
+		c.stats.FirstTime = at
+	}
+	if at.After(c.stats.LastTime) {
+		c.stats.LastTime = at
+	}
+}
+
+func (c *containerStats) observeDropped() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Dropped++
+}
+
+func (c *containerStats) observeReconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Reconnects++
+}
+
+func (c *containerStats) snapshot() ContainerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rv := c.stats
+	rv.Levels = make(map[string]int64, len(c.stats.Levels))
+	for k, v := range c.stats.Levels {
+		rv.Levels[k] = v
+	}
+	return rv
+}
+
+// maxStoppedContainerStats is the max statistics of the stopped log streams to keep.
+const maxStoppedContainerStats = 1000
+
+// streamStats collects the statistics of all log streams. The zero value is ready to use.
+type streamStats struct {
+	mu         sync.Mutex
+	containers map[string]*containerStats
+	// streams counts the running log streams of each container.
+	streams map[string]int
+	// stopped lists the containers in the order their log streams stopped.
+	stopped []string
+}
+
+// container returns the statistics of the pod container. The caller should call release
+// once the log stream stops.
+func (s *streamStats) container(namespace string, pod string, container string) *containerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.containers == nil {
+		s.containers = map[string]*containerStats{}
+		s.streams = map[string]int{}
+	}
+	key := containerStatsKey(namespace, pod, container)
+	c, exists := s.containers[key]
+	if !exists {
+		c = &containerStats{stats: ContainerStats{
+			Namespace: namespace,
+			Pod:       pod,
+			Container: container,
+			Levels:    map[string]int64{},
+		}}
+		s.containers[key] = c
+	}
+	s.streams[key]++
+	return c
+}
+
+// release marks the log stream of the pod container stopped. The statistics of the stopped
+// containers are kept for reporting, and evicted from the oldest stopped once exceeding
+// maxStoppedContainerStats.
+func (s *streamStats) release(namespace string, pod string, container string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := containerStatsKey(namespace, pod, container)
+	s.streams[key]--
+	if s.streams[key] > 0 {
+		return
+	}
+	delete(s.streams, key)
+	s.stopped = append(s.stopped, key)
+
+	for len(s.stopped) > maxStoppedContainerStats {
+		evicted := s.stopped[0]
+		s.stopped = s.stopped[1:]
+		if _, running := s.streams[evicted]; !running {
+			delete(s.containers, evicted)
+		}
+	}
+}
+
+func containerStatsKey(namespace string, pod string, container string) string {
+	return namespace + "/" + pod + "/" + container
+}
+
+func (s *streamStats) snapshot() Stats {
+	s.mu.Lock()
+	containers := make([]*containerStats, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	s.mu.Unlock()
+
+	var rv Stats
+	for _, c := range containers {
+		snapshot := c.snapshot()
+		rv.Containers = append(rv.Containers, snapshot)
+		rv.Lines += snapshot.Lines
+		rv.Bytes += snapshot.Bytes
+	}
+	sort.Slice(rv.Containers, func(i, j int) bool {
+		a, b := rv.Containers[i], rv.Containers[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Namespace+"/"+a.Pod+"/"+a.Container < b.Namespace+"/"+b.Pod+"/"+b.Container
+	})
+	return rv
+}
+
+// Stats returns a snapshot of the log streams statistics.
+func (s *Streamer) Stats() Stats {
+	return s.stats.snapshot()
+}
@@ -0,0 +1,169 @@
+package podstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStreamStats(t *testing.T) {
+	var stats streamStats
+
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	small := stats.container("ns", "small", "app")
+	small.observeLine("level=info msg=ok", at, true)
+	small.observeLine("level=error msg=failed", at.Add(time.Second), false)
+	small.observeReconnect()
+
+	large := stats.container("ns", "large", "app")
+	for i := 0; i < 4; i++ {
+		large.observeLine(fmt.Sprintf("level=debug msg=request %d", i), at.Add(time.Duration(i)*time.Second), true)
+	}
+	large.observeDropped()
+
+	snapshot := stats.snapshot()
+	assert.Equal(t, int64(6), snapshot.Lines)
+	if assert.Len(t, snapshot.Containers, 2) {
+		assert.Equal(t, "large", snapshot.Containers[0].Pod)
+		assert.Equal(t, int64(1), snapshot.Containers[0].Dropped)
+		assert.Equal(t, map[string]int64{"debug": 4}, snapshot.Containers[0].Levels)
+
+		assert.Equal(t, "small", snapshot.Containers[1].Pod)
+		assert.Equal(t, 0.5, snapshot.Containers[1].MatchRate())
+		assert.Equal(t, int64(1), snapshot.Containers[1].Reconnects)
+		assert.Equal(t, at, snapshot.Containers[1].FirstTime)
+		assert.Equal(t, at.Add(time.Second), snapshot.Containers[1].LastTime)
+	}
+
+	topTalkers := snapshot.TopTalkers(1)
+	if assert.Len(t, topTalkers, 1) {
+		assert.Equal(t, "large", topTalkers[0].Pod)
+	}
+	assert.Len(t, snapshot.TopTalkers(10), 2)
+	assert.Empty(t, snapshot.TopTalkers(-1))
+
+	// snapshots are not affected by later observations
+	large.observeLine("level=info msg=more", at, true)
+	assert.Equal(t, int64(4), snapshot.Containers[0].Levels["debug"])
+	assert.Equal(t, int64(7), stats.snapshot().Lines)
+}
+
+func TestStreamStats_Release(t *testing.T) {
+	var stats streamStats
+
+	stats.container("ns", "running", "app")
+	stats.container("ns", "restarted", "app")
+	stats.release("ns", "restarted", "app")
+	stats.container("ns", "restarted", "app")
+	for i := 0; i < maxStoppedContainerStats+1; i++ {
+		pod := fmt.Sprintf("stopped-%d", i)
+		stats.container("ns", pod, "app")
+		stats.release("ns", pod, "app")
+	}
+
+	snapshot := stats.snapshot()
+	assert.Len(t, snapshot.Containers, maxStoppedContainerStats+2, "oldest stopped stats should be evicted")
+	pods := map[string]bool{}
+	for _, c := range snapshot.Containers {
+		pods[c.Pod] = true
+	}
+	assert.True(t, pods["running"])
+	assert.True(t, pods["restarted"], "restarted stream should be kept")
+	assert.False(t, pods["stopped-0"])
+	assert.True(t, pods[fmt.Sprintf("stopped-%d", maxStoppedContainerStats)])
+}
+
+func TestStreamer_Stats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reported []Stats
+	testCtx := newBaseStreamerTestCtx(
+		t,
+		ReportStatsEvery(time.Hour, func(stats Stats) {
+			reported = append(reported, stats)
+		}),
+	)
+	for i := 0; i < 2; i++ {
+		_, err := testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testCtx.namespace,
+				Name:      fmt.Sprintf("test-pod-%d", i),
+				UID:       types.UID(fmt.Sprintf("test-pod-%d", i)),
+				Labels:    testCtx.labels,
+			},
+		}, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	err := testCtx.streamer.start(ctx.Done())
+	assert.NoError(t, err)
+
+	stats := testCtx.streamer.Stats()
+	assert.Equal(t, int64(2), stats.Lines)
+	if assert.Len(t, stats.Containers, 2) {
+		for _, container := range stats.Containers {
+			assert.Equal(t, int64(1), container.Lines)
+			assert.Equal(t, int64(len("fake logs")), container.Bytes)
+			assert.Equal(t, 1.0, container.MatchRate())
+		}
+	}
+
+	// the final statistics are reported once stopped
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, stats, reported[0])
+	}
+}
+
+func TestReportStatsEvery(t *testing.T) {
+	streamer := &Streamer{}
+	assert.Error(t, ReportStatsEvery(0, func(Stats) {})(streamer))
+	assert.Error(t, ReportStatsEvery(time.Second, nil)(streamer))
+	assert.NoError(t, ReportStatsEvery(time.Second, func(Stats) {})(streamer))
+	assert.Equal(t, time.Second, streamer.statsInterval)
+}
+
+func TestStreamer_reportStats(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inFlight int
+		calls    int
+	)
+	streamer := &Streamer{
+		statsInterval: time.Millisecond,
+		statsHandler: func(Stats) {
+			mu.Lock()
+			inFlight++
+			calls++
+			assert.Equal(t, 1, inFlight, "stats handler should not be called concurrently")
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		},
+	}
+
+	stop := streamer.reportStats()
+	time.Sleep(20 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	reported := calls
+	mu.Unlock()
+	assert.Greater(t, reported, 1)
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, reported, calls, "stats should not be reported once stopped")
+}
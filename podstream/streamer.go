@@ -3,7 +3,9 @@ package podstream
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"sync"
@@ -12,8 +14,10 @@ import (
 	"github.com/b4fun/kubekit/internal/logger"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
@@ -27,29 +31,46 @@ func Stream(
 	podsClient typedcorev1.PodInterface,
 	options ...Option,
 ) error {
+	streamer, err := NewStreamer(podsClient, options...)
+	if err != nil {
+		return err
+	}
+
+	return streamer.Run(stop)
+}
+
+// NewStreamer creates a streamer. Use Run to start streaming, and Stats to inspect the
+// log streams while running.
+func NewStreamer(podsClient typedcorev1.PodInterface, options ...Option) (*Streamer, error) {
 	streamer := &Streamer{
 		logsConsumer: LogEntryConsumers{},
 	}
 	for _, opt := range options {
 		if err := opt(streamer); err != nil {
-			return err
+			return nil, err
 		}
 	}
 	streamer.podsClient = podsClient
 	if streamer.logger == nil {
 		streamer.logger = logger.NoOp
 	}
+	if streamer.hooks == nil {
+		streamer.hooks = noopHooks{}
+	}
 	if streamer.emitLogsInterval < 1 {
 		streamer.emitLogsInterval = 1 * time.Second
 	}
 
-	return streamer.start(stop)
+	return streamer, nil
 }
 
 // Streamer streams pods logs.
 type Streamer struct {
 	logger logger.Logger
 
+	// hooks observes the streamer internals.
+	hooks Hooks
+
 	// podsClient is the client used to fetch pods.
 	podsClient typedcorev1.PodInterface
 
@@ -65,20 +86,86 @@ type Streamer struct {
 	// logFilter specifies the log filter to use.
 	logFilter LogFilter
 
+	// outputStreams specifies the output streams to consume. Empty means all.
+	outputStreams []OutputStream
+
+	// logsTransformers specifies the logs transformers to run before consuming.
+	logsTransformers LogEntryTransformers
+
 	// logsConsumer specifies the logs consumer to use.
 	logsConsumer LogEntryConsumer
 
+	// drain stops tracking new pods in follow mode once closed, the stream stops after
+	// the tracked pods logs have been consumed.
+	drain <-chan struct{}
+
+	// newestRevisionOnly specifies if only the pods of the newest revision should be streamed.
+	newestRevisionOnly bool
+
+	// appsClient is the client used to fetch the pods owners for the revision numbers.
+	appsClient typedappsv1.AppsV1Interface
+
+	// stopTerminatingPods specifies if terminating pods should stop being streamed.
+	stopTerminatingPods bool
+
+	// eventSources specifies the sources of events to emit along with the logs.
+	eventSources []eventSource
+
+	// rateLimit specifies the rate limits of each pod container log stream.
+	rateLimit RateLimit
+
+	// sampleTarget specifies the entries per second of each pod container log stream
+	// to keep before sampling. Zero disables sampling.
+	sampleTarget int
+
 	// emitLogsInterface speicifies the interval for emitting logs.
 	emitLogsInterval time.Duration
+
+	// stats collects the log streams statistics.
+	stats streamStats
+
+	// statsInterval specifies the interval for reporting statistics.
+	statsInterval time.Duration
+
+	// statsHandler specifies the handler to report statistics to.
+	statsHandler StatsHandler
+
+	// configLock guards the configurations changeable while running.
+	configLock sync.RWMutex
+
+	// addedConsumers specifies the logs consumers added while running.
+	addedConsumers []*addedConsumer
+
+	// paused indicates whether emitting logs is paused.
+	paused bool
+
+	// selectorChanged signals the running stream that the label selector has changed.
+	selectorChanged chan struct{}
+
+	// checkpointStore specifies the store to persist the log streams checkpoints.
+	checkpointStore CheckpointStore
+
+	// filePollInterval specifies the interval to poll the CRI log files for changes.
+	filePollInterval time.Duration
+}
+
+// Run starts the pod stream. It behaves like Stream.
+func (s *Streamer) Run(stop <-chan struct{}) error {
+	return s.start(stop)
 }
 
 func (s *Streamer) podsListOptions() metav1.ListOptions {
 	return metav1.ListOptions{
-		LabelSelector: s.labelSelector,
+		LabelSelector: s.currentLabelSelector(),
 	}
 }
 
 func (s *Streamer) start(stop <-chan struct{}) error {
+	if len(s.outputStreams) > 0 {
+		// the API server merges the output streams, no logs would be selected
+		return errors.New("output streams cannot be selected when streaming from the API server, use StreamCRILogs instead")
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -89,75 +176,278 @@ func (s *Streamer) start(stop <-chan struct{}) error {
 
 	buf := make(chan LogEntry, 128)
 
-	knownPods := map[types.UID]struct{}{}
+	selectorChanged := make(chan struct{}, 1)
+	s.configLock.Lock()
+	s.selectorChanged = selectorChanged
+	s.configLock.Unlock()
+
+	knownPods := map[types.UID]*trackedPod{}
 	knownPodsLock := &sync.Mutex{}
+	// stoppedPods tracks the pods untracked while running, to resume if tracked again
+	stoppedPods := map[types.UID]*trackedPod{}
+	eventObjects := newEventTargets()
+
+	var (
+		podWorks   sync.WaitGroup
+		eventWorks sync.WaitGroup
+		// trackingStopped is set once no more pods should be tracked
+		trackingStopped bool
+	)
+	// revisions tracks the newest revision of the pods
+	revisions := newestRevision{
+		resolve: func(pod *corev1.Pod) int64 {
+			return s.revisionNumber(ctx, pod)
+		},
+	}
+	stopTracking := func() {
+		knownPodsLock.Lock()
+		defer knownPodsLock.Unlock()
+
+		trackingStopped = true
+	}
 
-	var podWorks sync.WaitGroup
+	// untrackPod stops streaming the tracked pod. It resumes from the last read line if tracked again.
+	untrackPod := func(uid types.UID, tracked *trackedPod) {
+		tracked.cancel()
+		delete(knownPods, uid)
+		stoppedPods[uid] = tracked
+	}
 
 	// trackPod attempts to put the pod into log stream tracking
 	trackPod := func(pod *corev1.Pod) {
-		if pod.Status.Phase == corev1.PodPending {
-			// the pod is pending to be scheduled, skip it
+		knownPodsLock.Lock()
+		defer knownPodsLock.Unlock()
+
+		if trackingStopped {
 			return
 		}
 
-		knownPodsLock.Lock()
-		defer knownPodsLock.Unlock()
+		// events of pending pods, e.g. scheduling failures, are emitted as well
+		eventObjects.add(pod)
+
+		if s.newestRevisionOnly {
+			revision := podRevision(pod)
+			if revisions.observe(pod, revision) {
+				// a newer revision shows up, stop streaming the old ones. They are untracked so
+				// they can be streamed again if their revision becomes the newest again.
+				for uid, tracked := range knownPods {
+					if tracked.revision != revision {
+						s.logger.Log("stop streaming pod of old revision %s: %s", tracked.revision, uid)
+						untrackPod(uid, tracked)
+					}
+				}
+			}
+			if revision != revisions.newest {
+				return
+			}
+		}
 
-		if _, exists := knownPods[pod.UID]; exists {
+		if tracked, exists := knownPods[pod.UID]; exists {
+			if s.stopTerminatingPods && pod.DeletionTimestamp != nil {
+				s.logger.Log("stop streaming terminating pod: %s", pod.Name)
+				tracked.cancel()
+			}
 			// the pod is already tracked, skip it
 			return
 		}
+		if pod.Status.Phase == corev1.PodPending {
+			// the pod is pending to be scheduled, skip it
+			return
+		}
+		if s.stopTerminatingPods && pod.DeletionTimestamp != nil {
+			return
+		}
+
+		// the pod has been streamed before, resume after the lines read
+		stopped := stoppedPods[pod.UID]
+		delete(stoppedPods, pod.UID)
 
+		podCtx, podCancel := context.WithCancel(ctx)
+		tracked := &trackedPod{
+			revision: podRevision(pod),
+			labels:   pod.Labels,
+			cancel:   podCancel,
+			done:     make(chan struct{}),
+		}
 		podWorks.Add(1)
-		go func(podName string) {
+		go func(pod *corev1.Pod) {
 			defer podWorks.Done()
-			s.streamPod(ctx.Done(), podName, buf)
-		}(pod.GetName())
-		knownPods[pod.UID] = struct{}{}
+			defer close(tracked.done)
+			defer podCancel()
+
+			var resume Checkpoint
+			if stopped != nil {
+				// wait for the previous stream to stop
+				<-stopped.done
+				resume = stopped.last
+			}
+			tracked.last = s.streamPod(podCtx.Done(), pod, resume, buf)
+		}(pod.DeepCopy())
+		knownPods[pod.UID] = tracked
 	}
 
-	s.logger.Log("listing pods")
-	podsList, err := s.podsClient.List(ctx, s.podsListOptions())
-	if err != nil {
-		err = fmt.Errorf("list pods: %w", err)
-		s.logger.Log(err.Error())
-		return err
+	// listPods tracks the pods matching the label selector
+	listPods := func() error {
+		s.logger.Log("listing pods")
+		podsList, err := s.podsClient.List(ctx, s.podsListOptions())
+		if err != nil {
+			err = fmt.Errorf("list pods: %w", err)
+			s.logger.Log(err.Error())
+			return err
+		}
+		sort.Slice(podsList.Items, func(i, j int) bool {
+			return podsList.Items[i].Status.StartTime.Before(podsList.Items[j].Status.StartTime)
+		})
+		for idx := range podsList.Items {
+			trackPod(&podsList.Items[idx])
+		}
+		return nil
+	}
+
+	// untrackUnmatchedPods stops streaming the pods no longer matching the label selector
+	untrackUnmatchedPods := func() {
+		selector, err := labels.Parse(s.currentLabelSelector())
+		if err != nil {
+			s.logger.Log("failed to parse label selector: %s", err)
+			return
+		}
+
+		knownPodsLock.Lock()
+		defer knownPodsLock.Unlock()
+
+		for uid, tracked := range knownPods {
+			if !selector.Matches(labels.Set(tracked.labels)) {
+				s.logger.Log("stop streaming pod no longer selected: %s", uid)
+				untrackPod(uid, tracked)
+			}
+		}
+		eventObjects.removeUnmatched(selector)
 	}
-	sort.Slice(podsList.Items, func(i, j int) bool {
-		return podsList.Items[i].Status.StartTime.Before(podsList.Items[j].Status.StartTime)
-	})
-	for idx := range podsList.Items {
-		trackPod(&podsList.Items[idx])
+
+	if err := listPods(); err != nil {
+		return err
 	}
 
 	if s.follow {
-		go s.watch(ctx, trackPod)
+		go func() {
+			watchCtx, cancelWatch := context.WithCancel(ctx)
+			go s.watch(watchCtx, trackPod)
+
+			for {
+				select {
+				case <-ctx.Done():
+					cancelWatch()
+					return
+				case <-selectorChanged:
+					s.logger.Log("pods label selector has changed")
+					cancelWatch()
+					untrackUnmatchedPods()
+					// failures have been logged, the watch below picks up the pods as well
+					_ = listPods()
+
+					watchCtx, cancelWatch = context.WithCancel(ctx)
+					go s.watch(watchCtx, trackPod)
+				}
+			}
+		}()
+	}
+
+	isEventObject := func(uid types.UID) bool {
+		knownPodsLock.Lock()
+		defer knownPodsLock.Unlock()
+
+		return eventObjects.contains(uid)
+	}
+	for _, source := range s.eventSources {
+		eventWorks.Add(1)
+		go func(source eventSource) {
+			defer eventWorks.Done()
+			s.streamEvents(ctx, source, isEventObject, buf)
+		}(source)
 	}
 
 	consumeWork := make(chan struct{})
 	go func() {
 		defer close(consumeWork)
 
-		s.consumeLogs(ctx, buf)
+		s.consumeLogs(ctx.Done(), buf)
 	}()
 
+	if s.statsHandler != nil {
+		stopReportingStats := s.reportStats()
+		defer stopReportingStats()
+	}
+
 	if s.follow {
-		// in follow mode, wait until caller cancel
-		<-ctx.Done()
-		s.logger.Log("caller has cancelled the stream")
+		// in follow mode, wait until caller cancel or drain
+		select {
+		case <-ctx.Done():
+			s.logger.Log("caller has cancelled the stream")
+		case <-s.drain:
+			s.logger.Log("draining pod streams")
+			// track the pods created right before draining, which the watch may not have seen
+			_ = listPods()
+			stopTracking()
+
+			podsDone := make(chan struct{})
+			go func() {
+				defer close(podsDone)
+				podWorks.Wait()
+			}()
+			select {
+			case <-podsDone:
+			case <-ctx.Done():
+			}
+		}
 	} else {
 		// in non-follow mode, stop once all pods are tracked
 		podWorks.Wait()
-		s.logger.Log("pod workers have stopped")
-		cancel()
+		eventWorks.Wait()
 	}
+
+	stopTracking()
+	cancel()
+
+	// close the buffer after all writers stopped, so the consume worker can drain it
+	podWorks.Wait()
+	eventWorks.Wait()
+	s.logger.Log("pod workers have stopped")
+	close(buf)
 	<-consumeWork
 	s.logger.Log("consume worker has stopped")
 
 	return nil
 }
 
+// reportStats reports the statistics periodically until the returned stop function is called,
+// which reports the final statistics after the periodic reports have stopped, so the handler
+// is never called concurrently.
+func (s *Streamer) reportStats() (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(s.statsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.statsHandler(s.Stats())
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		s.statsHandler(s.Stats())
+	}
+}
+
 func (s *Streamer) watch(ctx context.Context, trackPods func(pod *corev1.Pod)) {
 	s.logger.Log("watching pods")
 	defer s.logger.Log("watch worker has stopped")
@@ -182,6 +472,7 @@ func (s *Streamer) watch(ctx context.Context, trackPods func(pod *corev1.Pod)) {
 		case event, ok := <-watcher.ResultChan():
 			if !ok {
 				s.logger.Log("reconnecting pods watcher")
+				s.hooks.WatchRestarted("pods")
 				watcher.Stop()
 				watcher, err = watchPods()
 				if err != nil {
@@ -202,53 +493,253 @@ func (s *Streamer) watch(ctx context.Context, trackPods func(pod *corev1.Pod)) {
 	}
 }
 
-func (s *Streamer) streamPod(stop <-chan struct{}, podName string, buf chan<- LogEntry) {
+// containerName resolves the container name to log from for the given pod.
+// An empty string is returned if the container cannot be determined.
+func (s *Streamer) containerName(pod *corev1.Pod) string {
+	if s.podLogOptions.Container != "" {
+		return s.podLogOptions.Container
+	}
+	if len(pod.Spec.Containers) == 1 {
+		// the API server defaults to the only container in the pod
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+// streamPod streams the logs of the pod until stopped or done. A non-zero resume position
+// resumes the stream after the lines read before. It returns the position of the lines read.
+func (s *Streamer) streamPod(
+	stop <-chan struct{},
+	pod *corev1.Pod,
+	resume Checkpoint,
+	buf chan<- LogEntry,
+) Checkpoint {
+	podName := pod.GetName()
+	containerName := s.containerName(pod)
+	revision := podRevision(pod)
+	stats := s.stats.container(pod.GetNamespace(), podName, containerName)
+	defer s.stats.release(pod.GetNamespace(), podName, containerName)
+
 	s.logger.Log("streaming pod: %s", podName)
 	defer s.logger.Log("pod stream has stopped: %s", podName)
+	s.hooks.PodStreamStarted()
+	defer s.hooks.PodStreamStopped()
 
 	streamCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	go func() {
+		// stop reading the log stream once stopped
+		select {
+		case <-stop:
+			cancel()
+		case <-streamCtx.Done():
+		}
+	}()
 
-	podLogOptions := s.podLogOptions.DeepCopy()
-	podLogOptions.Follow = s.follow
-	podLogOptions.Timestamps = true
-	stream, err := s.podsClient.GetLogs(podName, podLogOptions).Stream(streamCtx)
-	if err != nil {
-		s.logger.Log("failed to start log stream for pod %s: %s", podName, err)
-		return
+	limiter := s.newStreamLimiter()
+	defer func() {
+		if limiter.dropped > 0 {
+			s.logger.Log("dropped %d rate limited entries from pod %s", limiter.dropped, podName)
+		}
+	}()
+
+	// last is the position of the read lines, used for resuming broken streams
+	last := resume
+	if last.Time.IsZero() {
+		last, _ = s.loadCheckpoint(CheckpointKey{
+			Namespace: pod.GetNamespace(),
+			Pod:       podName,
+			Container: containerName,
+		})
 	}
-	defer stream.Close()
 
-	scanner := bufio.NewScanner(stream)
-	for scanner.Scan() {
+	for {
+		podLogOptions := s.podLogOptions.DeepCopy()
+		podLogOptions.Follow = s.follow
+		podLogOptions.Timestamps = true
+		if !last.Time.IsZero() {
+			// resume from the last read line or the checkpoint, the lines read in the overlap
+			// window are dropped
+			sinceTime := metav1.NewTime(last.Time)
+			podLogOptions.SinceTime = &sinceTime
+			podLogOptions.SinceSeconds = nil
+		}
+
+		stream, err := s.podsClient.GetLogs(podName, podLogOptions).Stream(streamCtx)
+		if err != nil {
+			s.logger.Log("failed to start log stream for pod %s: %s", podName, err)
+			s.hooks.StreamOpenFailed(streamErrorReason(err))
+			return last
+		}
+		err = s.readLogs(stop, stream, &last, func(timestamp time.Time, content string) bool {
+			entry := LogEntry{
+				Time:      timestamp,
+				Log:       content,
+				Namespace: pod.GetNamespace(),
+				Pod:       podName,
+				Container: containerName,
+				Revision:  revision,
+			}
+			return s.sendLine(stop, buf, entry, stats, limiter)
+		})
+		stream.Close()
+		if err == nil || !s.follow {
+			return last
+		}
+
 		select {
 		case <-stop:
-			return
+			return last
+		case <-time.After(time.Second):
+		}
+		s.logger.Log("reconnecting broken log stream for pod %s: %s", podName, err)
+		stats.observeReconnect()
+	}
+}
+
+// maxLogLineBytes is the max size of a log line read from the log stream.
+// Longer lines are truncated.
+const maxLogLineBytes = 1024 * 1024
+
+// readLogs reads the timestamped log lines from the stream and sends them until stopped or the
+// send returns false. The lines seen in the last position are skipped, and the position is
+// advanced with the read lines.
+func (s *Streamer) readLogs(
+	stop <-chan struct{},
+	stream io.Reader,
+	last *Checkpoint,
+	send func(timestamp time.Time, content string) bool,
+) error {
+	// resuming is set until the first line after the resume point, identical lines after that
+	// are new lines
+	resume := *last
+	resuming := !resume.Time.IsZero()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := readLogLine(reader, maxLogLineBytes)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
 		default:
 		}
 
-		line := scanner.Text()
 		parts := strings.SplitN(line, " ", 2)
-		content := parts[1]
 		timestamp, err := time.Parse(time.RFC3339, parts[0])
-		if err != nil {
+		var content string
+		if err != nil || len(parts) < 2 {
 			s.logger.Log("unable to decode log timestamp: %s", err)
 			// The current timestamp is the next best substitute. This won't be shown, but will be used
 			// for sorting
 			timestamp = time.Now()
 			content = line
+		} else {
+			content = parts[1]
+			if resuming {
+				if resume.seen(timestamp, content) {
+					// the line has been read before the stream is resumed
+					continue
+				}
+				resuming = false
+			}
+			*last = last.advance(timestamp, content)
+		}
+
+		if !send(timestamp, content) {
+			return nil
+		}
+	}
+}
+
+// readLogLine reads a line from the reader. Lines longer than maxBytes are truncated, and the
+// rest of them is skipped.
+func readLogLine(reader *bufio.Reader, maxBytes int) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+
+		if room := maxBytes - len(line); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			line = append(line, chunk...)
 		}
-		if s.logFilter == nil || s.logFilter.FilterLog(content) {
-			buf <- LogEntry{Time: timestamp, Log: content}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// sendLine filters and limits the log line read from a pod container, then sends it to the buffer.
+// It returns false if stopped.
+func (s *Streamer) sendLine(
+	stop <-chan struct{},
+	buf chan<- LogEntry,
+	entry LogEntry,
+	stats *containerStats,
+	limiter *streamLimiter,
+) bool {
+	logFilter := s.currentLogFilter()
+	matched := s.selectOutputStream(entry.Stream) && (logFilter == nil || logFilter.FilterLog(entry.Log))
+	stats.observeLine(entry.Log, entry.Time, matched)
+	if !matched {
+		s.hooks.EntriesObserved(EntryFiltered, 1)
+		return true
+	}
+
+	if !limiter.allow(&entry) {
+		stats.observeDropped()
+		s.hooks.EntriesObserved(EntryDropped, 1)
+		return true
+	}
+
+	select {
+	case <-stop:
+		return false
+	case buf <- entry:
+		return true
+	}
+}
+
+// selectOutputStream tells if the logs of the output stream should be consumed.
+func (s *Streamer) selectOutputStream(stream OutputStream) bool {
+	if len(s.outputStreams) < 1 {
+		return true
+	}
+	for _, selected := range s.outputStreams {
+		if stream == selected {
+			return true
 		}
 	}
+	return false
 }
 
-func (s *Streamer) consumeLogs(ctx context.Context, buf <-chan LogEntry) {
+// consumeLogs consumes logs from the buffer until the buffer is closed.
+// While paused, it stops reading from the buffer until resumed or done.
+func (s *Streamer) consumeLogs(done <-chan struct{}, buf <-chan LogEntry) {
 	ticker := time.NewTicker(s.emitLogsInterval)
 	defer ticker.Stop()
 
-	var unsorted logEntries
+	var (
+		unsorted    logEntries
+		checkpoints *checkpointTracker
+	)
+	if s.checkpointStore != nil {
+		checkpoints = newCheckpointTracker(s.checkpointStore)
+	}
 
 	sortThenSend := func() {
 		if len(unsorted) < 1 {
@@ -256,25 +747,64 @@ func (s *Streamer) consumeLogs(ctx context.Context, buf <-chan LogEntry) {
 		}
 
 		sort.Sort(unsorted)
-		s.logsConsumer.OnLogs(unsorted)
+		if checkpoints != nil {
+			// transformers might change the logs, track the positions before transforming
+			checkpoints.observe(unsorted)
+		}
+		logs := s.logsTransformers.TransformLogs(unsorted)
 		unsorted = nil
+		s.emitLogs(logs)
+
+		if checkpoints != nil {
+			if err := checkpoints.save(); err != nil {
+				s.logger.Log("failed to save checkpoints: %s", err)
+			}
+		}
 	}
 
 	// make sure all saved logs are emitted
-	defer sortThenSend()
+	defer func() {
+		sortThenSend()
 
+		s.emitLogs(s.logsTransformers.FlushLogs())
+	}()
+
+	// draining is set once done, all logs should be consumed regardless of pausing
+	draining := false
 	for {
+		input := buf
+		paused := !draining && s.Paused()
+		if paused {
+			input = nil
+		}
+
 		select {
-		case <-ctx.Done():
-			return
-		case logEntry, ok := <-buf:
+		case logEntry, ok := <-input:
 			if !ok {
 				return
 			}
 
 			unsorted = append(unsorted, logEntry)
 		case <-ticker.C:
-			sortThenSend()
+			s.hooks.BufferObserved(len(buf), cap(buf))
+			if !paused {
+				sortThenSend()
+			}
+		case <-done:
+			draining = true
+			done = nil
 		}
 	}
 }
+
+// emitLogs sends the logs to the logs consumer.
+func (s *Streamer) emitLogs(logs []LogEntry) {
+	if len(logs) < 1 {
+		return
+	}
+
+	consumeStart := time.Now()
+	s.currentConsumers().OnLogs(logs)
+	s.hooks.LogsConsumed(len(logs), time.Since(consumeStart))
+	s.hooks.EntriesObserved(EntryEmitted, len(logs))
+}
@@ -1,8 +1,11 @@
 package podstream
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -11,7 +14,12 @@ import (
 	"github.com/b4fun/kubekit/internal/logger"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -31,6 +39,7 @@ func newBaseStreamerTestCtx(t *testing.T, opts ...Option) *streamerTestCtx {
 	streamer := &Streamer{
 		logsConsumer:     LogEntryConsumers{},
 		logger:           logger.NoOp,
+		hooks:            noopHooks{},
 		emitLogsInterval: 1 * time.Second,
 		labelSelector:    "app=test",
 	}
@@ -88,6 +97,164 @@ func TestStreamer_NoFollow(t *testing.T) {
 		err := testCtx.streamer.start(ctx.Done())
 		assert.NoError(t, err)
 	})
+	t.Run("pod metadata", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newStreamerTestCtx(
+			t,
+			ConsumeLogsWithFunc(func(logs []LogEntry) {
+				loadedLogs = append(loadedLogs, logs...)
+			}),
+		)
+
+		testPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testCtx.namespace,
+				Name:      "test-pod",
+				Labels:    testCtx.labels,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+		}
+
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, testPod, metav1.CreateOptions{})
+
+		err := testCtx.streamer.start(ctx.Done())
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, loadedLogs) {
+			assert.Equal(t, testCtx.namespace, loadedLogs[0].Namespace)
+			assert.Equal(t, "test-pod", loadedLogs[0].Pod)
+			assert.Equal(t, "app", loadedLogs[0].Container)
+		}
+	})
+	t.Run("transform logs", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newStreamerTestCtx(
+			t,
+			TransformLogsWith(LogEntryTransformerFunc(func(logs []LogEntry) []LogEntry {
+				for idx := range logs {
+					logs[idx].Log = "transformed"
+				}
+				return logs
+			})),
+			ConsumeLogsWithFunc(func(logs []LogEntry) {
+				loadedLogs = append(loadedLogs, logs...)
+			}),
+		)
+
+		testPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testCtx.namespace,
+				Name:      "test-pod",
+				Labels:    testCtx.labels,
+			},
+		}
+
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, testPod, metav1.CreateOptions{})
+
+		err := testCtx.streamer.start(ctx.Done())
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, loadedLogs) {
+			assert.Equal(t, "transformed", loadedLogs[0].Log)
+		}
+	})
+	t.Run("events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var loadedLogs []LogEntry
+		testCtx := newStreamerTestCtx(
+			t,
+			ConsumeLogsWithFunc(func(logs []LogEntry) {
+				loadedLogs = append(loadedLogs, logs...)
+			}),
+		)
+		eventsClient := testCtx.fakeKubeClient.CoreV1().Events(testCtx.namespace)
+		assert.NoError(t, IncludeEvents(eventsClient)(testCtx.streamer))
+
+		testPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testCtx.namespace,
+				Name:      "test-pod",
+				UID:       "test-pod-uid",
+				Labels:    testCtx.labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "test-rs", UID: "test-rs-uid"},
+				},
+			},
+		}
+		testCtx.fakeKubeClient.CoreV1().Pods(testCtx.namespace).
+			Create(ctx, testPod, metav1.CreateOptions{})
+
+		eventTime := metav1.NewTime(time.Now().Add(-time.Minute))
+		for _, event := range []*corev1.Event{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testCtx.namespace, Name: "pod-event"},
+				InvolvedObject: corev1.ObjectReference{
+					Kind: "Pod", Name: "test-pod", UID: "test-pod-uid", FieldPath: "spec.containers{app}",
+				},
+				Type:          corev1.EventTypeWarning,
+				Reason:        "BackOff",
+				Message:       "Back-off restarting failed container",
+				LastTimestamp: eventTime,
+			},
+			{
+				ObjectMeta:     metav1.ObjectMeta{Namespace: testCtx.namespace, Name: "owner-event"},
+				InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "test-rs", UID: "test-rs-uid"},
+				Type:           corev1.EventTypeNormal,
+				Reason:         "SuccessfulCreate",
+				Message:        "Created pod: test-pod",
+				LastTimestamp:  eventTime,
+			},
+			{
+				ObjectMeta:     metav1.ObjectMeta{Namespace: testCtx.namespace, Name: "other-event"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "other-pod", UID: "other-pod-uid"},
+				Type:           corev1.EventTypeNormal,
+				Reason:         "Pulled",
+				LastTimestamp:  eventTime,
+			},
+		} {
+			_, err := eventsClient.Create(ctx, event, metav1.CreateOptions{})
+			assert.NoError(t, err)
+		}
+
+		err := testCtx.streamer.start(ctx.Done())
+		assert.NoError(t, err)
+
+		var events []LogEntry
+		for _, entry := range loadedLogs {
+			if entry.Kind == EntryKindEvent {
+				events = append(events, entry)
+			}
+		}
+		if assert.Len(t, events, 2) {
+			var messages []string
+			for _, event := range events {
+				messages = append(messages, event.Log)
+			}
+			assert.ElementsMatch(t, []string{
+				"Warning BackOff pod/test-pod: Back-off restarting failed container",
+				"Normal SuccessfulCreate replicaset/test-rs: Created pod: test-pod",
+			}, messages)
+		}
+		if assert.Len(t, loadedLogs, 3) {
+			assert.Equal(t, EntryKindLog, loadedLogs[2].Kind, "entries should be in time order")
+		}
+		for _, event := range events {
+			if event.Pod != "" {
+				assert.Equal(t, "test-pod", event.Pod)
+				assert.Equal(t, "app", event.Container)
+			}
+		}
+	})
 }
 
 func TestStreamer_Follow(t *testing.T) {
@@ -150,3 +317,216 @@ func TestStreamer_Follow(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestStreamer_OutputStreams(t *testing.T) {
+	testCtx := newBaseStreamerTestCtx(t, FromOutputStreams(StreamStderr))
+	createTestPod(t, testCtx, "pod-a", testCtx.labels)
+	actions := len(testCtx.fakeKubeClient.Actions())
+
+	err := testCtx.streamer.Run(make(chan struct{}))
+	assert.Error(t, err, "API server source cannot tell the output streams apart")
+	assert.Len(t, testCtx.fakeKubeClient.Actions(), actions, "pods should not be streamed")
+}
+
+func TestStreamer_readLogs(t *testing.T) {
+	streamer := &Streamer{logger: logger.NoOp}
+	stop := make(chan struct{})
+
+	var (
+		last Checkpoint
+		read []string
+	)
+	readLogs := func(lines ...string) {
+		stream := strings.NewReader(strings.Join(lines, "\n"))
+		err := streamer.readLogs(stop, stream, &last, func(_ time.Time, content string) bool {
+			read = append(read, content)
+			return true
+		})
+		assert.NoError(t, err)
+	}
+
+	readLogs(
+		"2022-01-01T00:00:00Z first",
+		"2022-01-01T00:00:01Z second",
+		"2022-01-01T00:00:01Z third",
+		"2022-01-01T00:00:01Z third",
+	)
+	assert.Equal(t, []string{"first", "second", "third", "third"}, read, "identical lines should be kept")
+
+	// the resumed stream starts from the time of the last read line
+	read = nil
+	readLogs(
+		"2022-01-01T00:00:01Z second",
+		"2022-01-01T00:00:01Z third",
+		"2022-01-01T00:00:01Z fourth",
+		"2022-01-01T00:00:02Z fifth",
+		"2022-01-01T00:00:02Z fifth",
+	)
+	assert.Equal(t, []string{"fourth", "fifth", "fifth"}, read, "distinct lines at the last time should be kept")
+}
+
+func TestReadLogLine(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	reader := bufio.NewReaderSize(strings.NewReader(long+"\nshort\nlast"), 16)
+
+	line, err := readLogLine(reader, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, long[:50], line, "long lines should be truncated")
+
+	line, err = readLogLine(reader, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", line)
+
+	line, err = readLogLine(reader, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, "last", line)
+
+	_, err = readLogLine(reader, 50)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestEventTargets(t *testing.T) {
+	newPod := func(uid string, app string, owner string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:             types.UID(uid),
+				Labels:          map[string]string{"app": app},
+				OwnerReferences: []metav1.OwnerReference{{UID: types.UID(owner)}},
+			},
+		}
+	}
+
+	targets := newEventTargets()
+	targets.add(newPod("pod-a", "a", "rs-1"))
+	targets.add(newPod("pod-a", "a", "rs-1"))
+	targets.add(newPod("pod-b", "b", "rs-1"))
+	targets.add(newPod("pod-c", "b", "rs-2"))
+	for _, uid := range []types.UID{"pod-a", "pod-b", "pod-c", "rs-1", "rs-2"} {
+		assert.True(t, targets.contains(uid), uid)
+	}
+
+	selector, err := labels.Parse("app=a")
+	assert.NoError(t, err)
+	targets.removeUnmatched(selector)
+	assert.True(t, targets.contains("pod-a"))
+	assert.True(t, targets.contains("rs-1"), "owner still referred by selected pods")
+	assert.False(t, targets.contains("pod-b"))
+	assert.False(t, targets.contains("pod-c"))
+	assert.False(t, targets.contains("rs-2"))
+}
+
+// expiringEventSource serves the events of the client, the first watch fails with an
+// expired resource version.
+type expiringEventSource struct {
+	coreEventSource
+
+	// beforeRelist is called before listing again
+	beforeRelist func()
+
+	mu      sync.Mutex
+	lists   int
+	watches int
+}
+
+func (s *expiringEventSource) list(ctx context.Context) ([]runtime.Object, string, error) {
+	s.mu.Lock()
+	s.lists++
+	lists := s.lists
+	s.mu.Unlock()
+
+	if lists == 2 && s.beforeRelist != nil {
+		s.beforeRelist()
+	}
+
+	return s.coreEventSource.list(ctx)
+}
+
+func (s *expiringEventSource) watch(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+	s.mu.Lock()
+	s.watches++
+	watches := s.watches
+	s.mu.Unlock()
+
+	if watches == 1 {
+		watcher := watch.NewFakeWithChanSize(1, false)
+		watcher.Error(&apierrors.NewResourceExpired("too old resource version").ErrStatus)
+		return watcher, nil
+	}
+	return s.coreEventSource.watch(ctx, resourceVersion)
+}
+
+func (s *expiringEventSource) counts() (lists int, watches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lists, s.watches
+}
+
+func TestStreamer_streamEvents_Expired(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCtx := newBaseStreamerTestCtx(t)
+	testCtx.streamer.follow = true
+	eventsClient := testCtx.fakeKubeClient.CoreV1().Events(testCtx.namespace)
+	source := &expiringEventSource{coreEventSource: coreEventSource{client: eventsClient}}
+
+	now := time.Now()
+	createEvent := func(name string, message string, at time.Time) {
+		_, err := eventsClient.Create(ctx, &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       testCtx.namespace,
+				Name:            name,
+				UID:             types.UID(name),
+				ResourceVersion: "1",
+			},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "test-pod", UID: "test-pod-uid"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Test",
+			Message:        message,
+			LastTimestamp:  metav1.NewTime(at),
+		}, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	createEvent("first", "first", now)
+	source.beforeRelist = func() {
+		// events missed by the expired watch, which are older than the emitted one
+		createEvent("missed-late", "missed late", now.Add(-time.Second))
+		createEvent("missed-early", "missed early", now.Add(-time.Minute))
+	}
+
+	buf := make(chan LogEntry, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		testCtx.streamer.streamEvents(ctx, source, func(types.UID) bool { return true }, buf)
+	}()
+
+	assert.Eventually(t, func() bool {
+		lists, watches := source.counts()
+		return lists == 2 && watches == 2
+	}, 5*time.Second, 10*time.Millisecond, "events should be listed again once expired")
+	// make sure the watch is established
+	time.Sleep(50 * time.Millisecond)
+	createEvent("second", "second", now.Add(time.Second))
+
+	var messages []string
+	for len(messages) < 4 {
+		select {
+		case entry := <-buf:
+			messages = append(messages, entry.Log)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got: %v", messages)
+		}
+	}
+	assert.Equal(t, []string{
+		"Normal Test pod/test-pod: first",
+		"Normal Test pod/test-pod: missed early",
+		"Normal Test pod/test-pod: missed late",
+		"Normal Test pod/test-pod: second",
+	}, messages, "missed events should be emitted in time order")
+
+	cancel()
+	<-done
+	assert.Empty(t, buf, "relisted events should not be emitted again")
+}
@@ -0,0 +1,136 @@
+package streammetrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exports the podstream internals as Prometheus metrics.
+//
+// It implements podstream.Hooks, use it with podstream.WithHooks. The metrics can be shared
+// by multiple streamers, in which case the buffer gauges report the last observed buffer.
+type Metrics struct {
+	namespace      string
+	constLabels    prometheus.Labels
+	latencyBuckets []float64
+
+	activeStreams    prometheus.Gauge
+	streamOpenErrors *prometheus.CounterVec
+	watchRestarts    *prometheus.CounterVec
+	entries          *prometheus.CounterVec
+	bufferLength     prometheus.Gauge
+	bufferCapacity   prometheus.Gauge
+	consumeLatency   prometheus.Histogram
+}
+
+var _ podstream.Hooks = (*Metrics)(nil)
+
+// New creates the stream metrics and registers them to the registry.
+func New(registry prometheus.Registerer, options ...Option) (*Metrics, error) {
+	if registry == nil {
+		return nil, errors.New("registry is required")
+	}
+
+	metrics := &Metrics{
+		namespace:      "podstream",
+		latencyBuckets: prometheus.DefBuckets,
+	}
+	for _, opt := range options {
+		if err := opt(metrics); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics.activeStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   metrics.namespace,
+		Name:        "active_pod_streams",
+		Help:        "Number of pods being streamed.",
+		ConstLabels: metrics.constLabels,
+	})
+	metrics.streamOpenErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   metrics.namespace,
+		Name:        "stream_open_errors_total",
+		Help:        "Number of pod log streams failed to open, by reason.",
+		ConstLabels: metrics.constLabels,
+	}, []string{"reason"})
+	metrics.watchRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   metrics.namespace,
+		Name:        "watch_restarts_total",
+		Help:        "Number of watches restarted after closed by the server, by resource.",
+		ConstLabels: metrics.constLabels,
+	}, []string{"resource"})
+	metrics.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   metrics.namespace,
+		Name:        "entries_total",
+		Help:        "Number of log entries, by outcome of emitted, dropped or filtered.",
+		ConstLabels: metrics.constLabels,
+	}, []string{"outcome"})
+	metrics.bufferLength = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   metrics.namespace,
+		Name:        "buffer_entries",
+		Help:        "Number of entries read but not yet consumed in the buffer.",
+		ConstLabels: metrics.constLabels,
+	})
+	metrics.bufferCapacity = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   metrics.namespace,
+		Name:        "buffer_capacity_entries",
+		Help:        "Capacity of the buffer.",
+		ConstLabels: metrics.constLabels,
+	})
+	metrics.consumeLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   metrics.namespace,
+		Name:        "consume_duration_seconds",
+		Help:        "Time spent by the logs consumer.",
+		ConstLabels: metrics.constLabels,
+		Buckets:     metrics.latencyBuckets,
+	})
+
+	for _, collector := range []prometheus.Collector{
+		metrics.activeStreams,
+		metrics.streamOpenErrors,
+		metrics.watchRestarts,
+		metrics.entries,
+		metrics.bufferLength,
+		metrics.bufferCapacity,
+		metrics.consumeLatency,
+	} {
+		if err := registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("register metrics: %w", err)
+		}
+	}
+
+	return metrics, nil
+}
+
+func (m *Metrics) PodStreamStarted() {
+	m.activeStreams.Inc()
+}
+
+func (m *Metrics) PodStreamStopped() {
+	m.activeStreams.Dec()
+}
+
+func (m *Metrics) StreamOpenFailed(reason string) {
+	m.streamOpenErrors.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) WatchRestarted(resource string) {
+	m.watchRestarts.WithLabelValues(resource).Inc()
+}
+
+func (m *Metrics) EntriesObserved(outcome podstream.EntryOutcome, count int) {
+	m.entries.WithLabelValues(string(outcome)).Add(float64(count))
+}
+
+func (m *Metrics) BufferObserved(length int, capacity int) {
+	m.bufferLength.Set(float64(length))
+	m.bufferCapacity.Set(float64(capacity))
+}
+
+func (m *Metrics) LogsConsumed(count int, latency time.Duration) {
+	m.consumeLatency.Observe(latency.Seconds())
+}
@@ -0,0 +1,101 @@
+package streammetrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry, WithConstLabels(prometheus.Labels{"collector": "test"}))
+	assert.NoError(t, err)
+
+	metrics.PodStreamStarted()
+	metrics.PodStreamStarted()
+	metrics.PodStreamStopped()
+	metrics.StreamOpenFailed("NotFound")
+	metrics.WatchRestarted("pods")
+	metrics.EntriesObserved(podstream.EntryEmitted, 3)
+	metrics.EntriesObserved(podstream.EntryFiltered, 1)
+	metrics.BufferObserved(10, 128)
+	metrics.LogsConsumed(3, 20*time.Millisecond)
+
+	expected := `
+# HELP podstream_active_pod_streams Number of pods being streamed.
+# TYPE podstream_active_pod_streams gauge
+podstream_active_pod_streams{collector="test"} 1
+# HELP podstream_buffer_entries Number of entries read but not yet consumed in the buffer.
+# TYPE podstream_buffer_entries gauge
+podstream_buffer_entries{collector="test"} 10
+# HELP podstream_entries_total Number of log entries, by outcome of emitted, dropped or filtered.
+# TYPE podstream_entries_total counter
+podstream_entries_total{collector="test",outcome="emitted"} 3
+podstream_entries_total{collector="test",outcome="filtered"} 1
+# HELP podstream_stream_open_errors_total Number of pod log streams failed to open, by reason.
+# TYPE podstream_stream_open_errors_total counter
+podstream_stream_open_errors_total{collector="test",reason="NotFound"} 1
+# HELP podstream_watch_restarts_total Number of watches restarted after closed by the server, by resource.
+# TYPE podstream_watch_restarts_total counter
+podstream_watch_restarts_total{collector="test",resource="pods"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(
+		registry, strings.NewReader(expected),
+		"podstream_active_pod_streams",
+		"podstream_buffer_entries",
+		"podstream_entries_total",
+		"podstream_stream_open_errors_total",
+		"podstream_watch_restarts_total",
+	))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.consumeLatency))
+
+	// registering twice to the same registry fails
+	_, err = New(registry)
+	assert.Error(t, err)
+
+	_, err = New(nil)
+	assert.Error(t, err)
+}
+
+func TestMetrics_Stream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := fake.NewSimpleClientset()
+	for i := 0; i < 2; i++ {
+		_, err := client.CoreV1().Pods("ns").Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      fmt.Sprintf("pod-%d", i),
+				UID:       types.UID(fmt.Sprintf("pod-%d", i)),
+				Labels:    map[string]string{"app": "test"},
+			},
+		}, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	metrics, err := New(prometheus.NewRegistry())
+	assert.NoError(t, err)
+
+	err = podstream.Stream(
+		ctx.Done(),
+		client.CoreV1().Pods("ns"),
+		podstream.FromSelectedPods("app=test"),
+		podstream.WithHooks(metrics),
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.activeStreams))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.entries.WithLabelValues(string(podstream.EntryEmitted))))
+}
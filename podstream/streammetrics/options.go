@@ -0,0 +1,37 @@
+package streammetrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithNamespace sets the namespace prefixing the metric names.
+// Defaults to "podstream".
+func WithNamespace(namespace string) Option {
+	return func(metrics *Metrics) error {
+		metrics.namespace = namespace
+		return nil
+	}
+}
+
+// WithConstLabels sets the labels added to all the metrics, e.g. to tell collectors apart.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(metrics *Metrics) error {
+		metrics.constLabels = labels
+		return nil
+	}
+}
+
+// WithLatencyBuckets sets the buckets of the consumer latency histogram.
+// Defaults to prometheus.DefBuckets.
+func WithLatencyBuckets(buckets []float64) Option {
+	return func(metrics *Metrics) error {
+		if len(buckets) < 1 {
+			return errors.New("latency buckets are required")
+		}
+
+		metrics.latencyBuckets = buckets
+		return nil
+	}
+}
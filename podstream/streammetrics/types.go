@@ -0,0 +1,4 @@
+package streammetrics
+
+// Option specifies options for configuring the stream metrics.
+type Option func(metrics *Metrics) error
@@ -0,0 +1,136 @@
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+const (
+	nilValue = "-"
+
+	// DefaultStructuredDataID is the default SD-ID of the pod metadata element.
+	// 32473 is the private enterprise number reserved for documentation (RFC 5612).
+	DefaultStructuredDataID = "k8s@32473"
+
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxProcIDLen   = 128
+	maxSDNameLen   = 32
+)
+
+var severities = map[podstream.Level]Severity{
+	podstream.LevelTrace:   SeverityDebug,
+	podstream.LevelDebug:   SeverityDebug,
+	podstream.LevelInfo:    SeverityInformational,
+	podstream.LevelWarn:    SeverityWarning,
+	podstream.LevelError:   SeverityError,
+	podstream.LevelFatal:   SeverityCritical,
+	podstream.LevelUnknown: SeverityNotice,
+}
+
+// formatter formats log entries as RFC 5424 messages.
+type formatter struct {
+	hostname string
+	appName  string
+	facility Facility
+	sdID     string
+	// stderrSeverity is the severity of stderr logs without a known level, if set.
+	stderrSeverity *Severity
+}
+
+// headerField sanitizes a header field to printable US-ASCII with max length.
+func headerField(v string, maxLen int) string {
+	if v == "" {
+		return nilValue
+	}
+
+	var b strings.Builder
+	for _, r := range v {
+		if b.Len() >= maxLen {
+			break
+		}
+		if r < 33 || r > 126 {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeParamValue escapes the characters RFC 5424 requires in PARAM-VALUE.
+func escapeParamValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
+
+func (f *formatter) structuredData(entry podstream.LogEntry) string {
+	var b strings.Builder
+	for _, param := range []struct{ name, value string }{
+		{"namespace", entry.Namespace},
+		{"pod", entry.Pod},
+		{"container", entry.Container},
+		{"stream", string(entry.Stream)},
+	} {
+		if param.value == "" {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(param.name)
+		b.WriteString(`="`)
+		b.WriteString(escapeParamValue(param.value))
+		b.WriteByte('"')
+	}
+	if b.Len() < 1 {
+		return nilValue
+	}
+
+	return "[" + headerField(f.sdID, maxSDNameLen) + b.String() + "]"
+}
+
+// severity returns the severity of the log entry.
+func (f *formatter) severity(entry podstream.LogEntry) Severity {
+	level := podstream.ParseLevel(entry.Log)
+	if level == podstream.LevelUnknown && entry.Stream == podstream.StreamStderr && f.stderrSeverity != nil {
+		return *f.stderrSeverity
+	}
+	return severities[level]
+}
+
+// format formats the log entry as a RFC 5424 message.
+func (f *formatter) format(entry podstream.LogEntry) []byte {
+	pri := int(f.facility)*8 + int(f.severity(entry))
+
+	appName := f.appName
+	if appName == "" {
+		appName = entry.Container
+	}
+
+	timestamp := nilValue
+	if !entry.Time.IsZero() {
+		timestamp = entry.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(pri))
+	b.WriteString(">1 ")
+	b.WriteString(timestamp)
+	b.WriteByte(' ')
+	b.WriteString(headerField(f.hostname, maxHostnameLen))
+	b.WriteByte(' ')
+	b.WriteString(headerField(appName, maxAppNameLen))
+	b.WriteByte(' ')
+	b.WriteString(headerField(entry.Pod, maxProcIDLen))
+	b.WriteByte(' ')
+	b.WriteString(nilValue) // MSGID
+	b.WriteByte(' ')
+	b.WriteString(f.structuredData(entry))
+	if entry.Log != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Log)
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,106 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the sink.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(sink *Sink) error {
+		sink.logger = logger
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS config for TransportTLS.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(sink *Sink) error {
+		sink.tlsConfig = config
+		return nil
+	}
+}
+
+// WithHostname sets the HOSTNAME field of the messages.
+// Defaults to the host name of the current machine.
+func WithHostname(hostname string) Option {
+	return func(sink *Sink) error {
+		sink.formatter.hostname = hostname
+		return nil
+	}
+}
+
+// WithAppName sets the APP-NAME field of the messages.
+// Defaults to the container name of the log entry.
+func WithAppName(appName string) Option {
+	return func(sink *Sink) error {
+		sink.formatter.appName = appName
+		return nil
+	}
+}
+
+// WithFacility sets the facility of the messages. Defaults to FacilityUser.
+func WithFacility(facility Facility) Option {
+	return func(sink *Sink) error {
+		if facility < 0 || facility > 23 {
+			return errors.New("facility must be in [0, 23]")
+		}
+
+		sink.formatter.facility = facility
+		return nil
+	}
+}
+
+// WithStructuredDataID sets the SD-ID of the structured data element carrying pod metadata.
+func WithStructuredDataID(id string) Option {
+	return func(sink *Sink) error {
+		if id == "" {
+			return errors.New("structured data ID is required")
+		}
+
+		sink.formatter.sdID = id
+		return nil
+	}
+}
+
+// WithStderrSeverity sets the severity of the logs written to stderr without a known level,
+// e.g. SeverityWarning to treat them as warnings. Defaults to the severity of unknown levels.
+func WithStderrSeverity(severity Severity) Option {
+	return func(sink *Sink) error {
+		if severity < SeverityEmergency || severity > SeverityDebug {
+			return errors.New("severity must be in [0, 7]")
+		}
+
+		sink.formatter.stderrSeverity = &severity
+		return nil
+	}
+}
+
+// WithTimeout sets the dial and write timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(sink *Sink) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		sink.timeout = timeout
+		return nil
+	}
+}
+
+// WithReconnect sets the max reconnect attempts for a batch of messages and the backoff between
+// attempts. The rest of the batch is dropped once the attempts are used up.
+func WithReconnect(maxAttempts int, backoff time.Duration) Option {
+	return func(sink *Sink) error {
+		if maxAttempts < 0 {
+			return errors.New("max attempts must not be negative")
+		}
+
+		sink.maxReconnects = maxAttempts
+		sink.reconnectBackoff = backoff
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Sink is a podstream.LogEntryConsumer that sends log entries as RFC 5424 syslog messages.
+//
+// Each message carries the pod metadata as structured data, the container name as APP-NAME
+// and the pod name as PROCID. The connection is established lazily and re-established
+// when writes fail.
+type Sink struct {
+	logger logger.Logger
+
+	// transport and address specify the syslog receiver.
+	transport Transport
+	address   string
+
+	// tlsConfig specifies the TLS config for TransportTLS.
+	tlsConfig *tls.Config
+
+	// timeout specifies the dial and write timeout.
+	timeout time.Duration
+
+	// maxReconnects specifies the max reconnect attempts for a batch of messages.
+	maxReconnects int
+
+	// reconnectBackoff specifies the backoff between reconnect attempts.
+	reconnectBackoff time.Duration
+
+	formatter formatter
+
+	connLock sync.Mutex
+	conn     net.Conn
+}
+
+var _ podstream.LogEntryConsumer = (*Sink)(nil)
+
+// NewSink creates a syslog sink sending to the address with the given transport.
+func NewSink(transport Transport, address string, options ...Option) (*Sink, error) {
+	switch transport {
+	case TransportUDP, TransportTCP, TransportTLS:
+	default:
+		return nil, fmt.Errorf("unsupported transport: %q", transport)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+
+	hostname, _ := os.Hostname()
+	sink := &Sink{
+		logger:           logger.NoOp,
+		transport:        transport,
+		address:          address,
+		timeout:          10 * time.Second,
+		maxReconnects:    3,
+		reconnectBackoff: 1 * time.Second,
+		formatter: formatter{
+			hostname: hostname,
+			facility: FacilityUser,
+			sdID:     DefaultStructuredDataID,
+		},
+	}
+	for _, opt := range options {
+		if err := opt(sink); err != nil {
+			return nil, err
+		}
+	}
+	if sink.logger == nil {
+		sink.logger = logger.NoOp
+	}
+
+	return sink, nil
+}
+
+func (s *Sink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	switch s.transport {
+	case TransportTLS:
+		return tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	default:
+		return dialer.Dial(string(s.transport), s.address)
+	}
+}
+
+// frame frames the message for the transport.
+func (s *Sink) frame(msg []byte) []byte {
+	if s.transport == TransportUDP {
+		return msg
+	}
+
+	// octet-counting: MSG-LEN SP SYSLOG-MSG
+	framed := strconv.AppendInt(nil, int64(len(msg)), 10)
+	framed = append(framed, ' ')
+	return append(framed, msg...)
+}
+
+// closeConn closes current connection. It expects the caller holds the connLock.
+func (s *Sink) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// write writes the framed message. It expects the caller holds the connLock.
+func (s *Sink) write(framed []byte) error {
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return fmt.Errorf("dial %s %s: %w", s.transport, s.address, err)
+		}
+		s.conn = conn
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(framed); err != nil {
+		s.closeConn()
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	return nil
+}
+
+// OnLogs sends the logs. The reconnect attempts are shared by the logs, once they are used up,
+// the rest of the logs are dropped, so an unavailable receiver cannot block the caller for long.
+func (s *Sink) OnLogs(logs []podstream.LogEntry) {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	reconnects := 0
+	for idx, entry := range logs {
+		framed := s.frame(s.formatter.format(entry))
+
+		err := s.write(framed)
+		for err != nil && reconnects < s.maxReconnects {
+			reconnects++
+			s.logger.Log("reconnecting syslog (attempt %d): %s", reconnects, err)
+			time.Sleep(s.reconnectBackoff)
+			err = s.write(framed)
+		}
+		if err != nil {
+			s.logger.Log("dropping %d syslog messages: %s", len(logs)-idx, err)
+			return
+		}
+	}
+}
+
+// Close closes the underlying connection.
+func (s *Sink) Close() error {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	s.closeConn()
+	return nil
+}
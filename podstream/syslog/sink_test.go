@@ -0,0 +1,262 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit"
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEntry = podstream.LogEntry{
+	Time:      time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC),
+	Log:       `level=error msg="failed"`,
+	Namespace: "ns",
+	Pod:       "pod-1",
+	Container: "app",
+}
+
+const testMessage = `<11>1 2022-05-01T10:00:00Z node-1 app pod-1 - [k8s@32473 namespace="ns" pod="pod-1" container="app"] level=error msg="failed"`
+
+func TestFormatter(t *testing.T) {
+	f := &formatter{hostname: "node-1", facility: FacilityUser, sdID: DefaultStructuredDataID}
+	assert.Equal(t, testMessage, string(f.format(testEntry)))
+
+	f = &formatter{facility: FacilityLocal0, sdID: "test"}
+	assert.Equal(
+		t,
+		`<134>1 - - - a"b] - [test pod="a\"b\]"] level=info started`,
+		string(f.format(podstream.LogEntry{Log: "level=info started", Pod: `a"b]`})),
+	)
+
+	warning := SeverityWarning
+	f = &formatter{facility: FacilityUser, sdID: "test", stderrSeverity: &warning}
+	assert.Equal(
+		t,
+		`<12>1 - - - - - [test stream="stderr"] connection refused`,
+		string(f.format(podstream.LogEntry{Log: "connection refused", Stream: podstream.StreamStderr})),
+	)
+	assert.Equal(
+		t,
+		`<14>1 - - - - - [test stream="stderr"] level=info started`,
+		string(f.format(podstream.LogEntry{Log: "level=info started", Stream: podstream.StreamStderr})),
+		"known levels should be kept",
+	)
+	assert.Equal(
+		t,
+		`<13>1 - - - - - [test stream="stdout"] started`,
+		string(f.format(podstream.LogEntry{Log: "started", Stream: podstream.StreamStdout})),
+	)
+}
+
+// readOctetCounted reads a single octet-counting framed message.
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	lenStr, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	msgLen, err := strconv.Atoi(strings.TrimSpace(lenStr))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, msgLen)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+	return string(msg), nil
+}
+
+func TestSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSink(TransportUDP, conn.LocalAddr().String(), WithHostname("node-1"))
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	sink.OnLogs([]podstream.LogEntry{testEntry})
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, string(buf[:n]))
+}
+
+func TestSink_TCPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	messages := make(chan string, 100)
+	connections := make(chan int, 10)
+	go func() {
+		for connIdx := 0; ; connIdx++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections <- connIdx
+			go func(connIdx int, conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := readOctetCounted(r)
+					if err != nil {
+						return
+					}
+					messages <- msg
+					if connIdx == 0 {
+						// drop the first connection after the first message
+						return
+					}
+				}
+			}(connIdx, conn)
+		}
+	}()
+
+	sink, err := NewSink(
+		TransportTCP,
+		listener.Addr().String(),
+		WithHostname("node-1"),
+		WithReconnect(3, 10*time.Millisecond),
+	)
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	sink.OnLogs([]podstream.LogEntry{testEntry})
+	assert.Equal(t, testMessage, <-messages)
+	assert.Equal(t, 0, <-connections)
+
+	// writes to the dropped connection might succeed before the failure is noticed,
+	// keep sending until the sink reconnects.
+	deadline := time.After(5 * time.Second)
+	for {
+		sink.OnLogs([]podstream.LogEntry{testEntry})
+		select {
+		case connIdx := <-connections:
+			assert.Equal(t, 1, connIdx)
+			assert.Equal(t, testMessage, <-messages)
+			return
+		case <-deadline:
+			t.Fatal("sink did not reconnect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSink_ReceiverDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+	sink, err := NewSink(
+		TransportTCP,
+		address,
+		WithLogger(kubekit.LogFunc(func(msg string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			logs = append(logs, fmt.Sprintf(msg, args...))
+		})),
+		WithReconnect(2, 50*time.Millisecond),
+	)
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	batch := make([]podstream.LogEntry, 10)
+	for idx := range batch {
+		batch[idx] = testEntry
+	}
+	started := time.Now()
+	sink.OnLogs(batch)
+	assert.Less(t, time.Since(started), 500*time.Millisecond, "reconnects should be shared by the batch")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, logs, 3) {
+		assert.True(t, strings.HasPrefix(logs[2], "dropping 10 syslog messages"), logs[2])
+	}
+}
+
+func newTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestSink_TLS(t *testing.T) {
+	cert := newTestCertificate(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		msg, err := readOctetCounted(bufio.NewReader(conn))
+		if err == nil {
+			messages <- msg
+		}
+	}()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(leaf)
+
+	sink, err := NewSink(
+		TransportTLS,
+		listener.Addr().String(),
+		WithHostname("node-1"),
+		WithTLSConfig(&tls.Config{RootCAs: rootCAs}),
+	)
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	sink.OnLogs([]podstream.LogEntry{testEntry})
+
+	select {
+	case msg := <-messages:
+		assert.Equal(t, testMessage, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
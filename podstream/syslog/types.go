@@ -0,0 +1,46 @@
+package syslog
+
+// Transport specifies the transport to send syslog messages over.
+type Transport string
+
+const (
+	// TransportUDP sends each message in a single datagram (RFC 5426).
+	TransportUDP Transport = "udp"
+	// TransportTCP sends messages with octet-counting framing (RFC 6587).
+	TransportTCP Transport = "tcp"
+	// TransportTLS sends messages with octet-counting framing over TLS (RFC 5425).
+	TransportTLS Transport = "tls"
+)
+
+// Facility is the syslog facility code.
+type Facility int
+
+const (
+	FacilityUser   Facility = 1
+	FacilityDaemon Facility = 3
+	FacilityLocal0 Facility = 16
+	FacilityLocal1 Facility = 17
+	FacilityLocal2 Facility = 18
+	FacilityLocal3 Facility = 19
+	FacilityLocal4 Facility = 20
+	FacilityLocal5 Facility = 21
+	FacilityLocal6 Facility = 22
+	FacilityLocal7 Facility = 23
+)
+
+// Severity is the syslog severity code.
+type Severity int
+
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInformational
+	SeverityDebug
+)
+
+// Option specifies options for configuring the syslog sink.
+type Option func(sink *Sink) error
@@ -4,12 +4,60 @@ import (
 	"time"
 )
 
+// EntryKind is the kind of log entry.
+type EntryKind string
+
+const (
+	// EntryKindLog is the kind of container log entries. It is the zero value.
+	EntryKindLog EntryKind = ""
+	// EntryKindEvent is the kind of Kubernetes Event entries.
+	EntryKindEvent EntryKind = "event"
+)
+
+// OutputStream is the output stream of a container.
+type OutputStream string
+
+const (
+	// StreamStdout is the standard output stream.
+	StreamStdout OutputStream = "stdout"
+	// StreamStderr is the standard error stream.
+	StreamStderr OutputStream = "stderr"
+)
+
 // LogEntry represents a single log entry.
 type LogEntry struct {
+	// Kind is the kind of the entry.
+	Kind EntryKind `json:"kind,omitempty"`
 	// Time is the log time.
 	Time time.Time `json:"time"`
 	// Log is the log message.
 	Log string `json:"log"`
+	// Cluster is the name of the cluster of the pod emitting the log.
+	// It is empty unless streaming from multiple clusters.
+	Cluster string `json:"cluster,omitempty"`
+	// Namespace is the namespace of the pod emitting the log.
+	Namespace string `json:"namespace,omitempty"`
+	// Pod is the name of the pod emitting the log.
+	Pod string `json:"pod,omitempty"`
+	// Container is the name of the container emitting the log.
+	// It is empty when the container cannot be determined.
+	Container string `json:"container,omitempty"`
+	// Stream is the output stream of the container emitting the log. It is empty when the source
+	// does not tell the streams apart, e.g. the API server merges them.
+	Stream OutputStream `json:"stream,omitempty"`
+	// Revision is the workload revision of the pod emitting the log, i.e. the pod-template-hash
+	// of ReplicaSet pods or the controller-revision-hash of StatefulSet and DaemonSet pods.
+	// It is empty when the pod has no revision.
+	Revision string `json:"revision,omitempty"`
+	// Repeats is the number of repeated lines collapsed into the entry.
+	// It is zero for entries not collapsed.
+	Repeats int `json:"repeats,omitempty"`
+	// LastTime is the time of the last repeated line collapsed into the entry.
+	// It is nil for entries not collapsed.
+	LastTime *time.Time `json:"lastTime,omitempty"`
+	// SampleRate is the rate the entry is sampled at, i.e. the entry stands for SampleRate
+	// entries. It is zero for entries not sampled.
+	SampleRate int `json:"sampleRate,omitempty"`
 }
 
 // LogEntryConsumer consumes log entries.
@@ -25,6 +73,26 @@ func (f LogEntryConsumerFunc) OnLogs(logs []LogEntry) {
 	f(logs)
 }
 
+// LogEntryTransformer transforms log entries before they are consumed.
+type LogEntryTransformer interface {
+	// TransformLogs returns the transformed log entries.
+	// The returned slice can be shorter or longer than the input.
+	TransformLogs(logs []LogEntry) []LogEntry
+}
+
+// LogEntryTransformerFunc is a function that transforms log entries.
+type LogEntryTransformerFunc func(logs []LogEntry) []LogEntry
+
+func (f LogEntryTransformerFunc) TransformLogs(logs []LogEntry) []LogEntry {
+	return f(logs)
+}
+
+// LogEntryFlusher is implemented by transformers holding log entries between calls.
+type LogEntryFlusher interface {
+	// FlushLogs returns the held log entries. It is called once the stream stops.
+	FlushLogs() []LogEntry
+}
+
 // LogFilter filters log line.
 type LogFilter interface {
 	// FilterLog returns true if the log line should be consumed.
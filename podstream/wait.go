@@ -0,0 +1,56 @@
+package podstream
+
+import (
+	"context"
+	"sync"
+
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// WaitForLog follows the logs of the selected pods and returns the first entry matching the
+// regex pattern. It returns the context error if the context is done before any match.
+//
+// Additional options can be used to narrow down the logs, e.g. FromContainer or Since.
+func WaitForLog(
+	ctx context.Context,
+	podsClient typedcorev1.PodInterface,
+	labelSelector string,
+	pattern string,
+	options ...Option,
+) (LogEntry, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		matched LogEntry
+		found   bool
+		mu      sync.Mutex
+	)
+
+	options = append(
+		append([]Option{}, options...),
+		FollowSelectedPods(labelSelector),
+		FilterWithRegex(pattern),
+		ConsumeLogsWithFunc(func(logs []LogEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if found || len(logs) < 1 {
+				return
+			}
+			matched = logs[0]
+			found = true
+			cancel()
+		}),
+	)
+	if err := Stream(ctx.Done(), podsClient, options...); err != nil {
+		return LogEntry{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if found {
+		return matched, nil
+	}
+	return LogEntry{}, ctx.Err()
+}
@@ -0,0 +1,48 @@
+package podstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaitForLog(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := fake.NewSimpleClientset()
+	podsClient := client.CoreV1().Pods("test")
+	_, err := podsClient.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test",
+			Name:      "test-pod",
+			Labels:    map[string]string{"app": "test"},
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	t.Run("matched", func(t *testing.T) {
+		entry, err := WaitForLog(ctx, podsClient, "app=test", "^fake")
+		assert.NoError(t, err)
+		assert.Equal(t, "fake logs", entry.Log)
+		assert.Equal(t, "test-pod", entry.Pod)
+	})
+
+	t.Run("not matched", func(t *testing.T) {
+		waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer waitCancel()
+
+		_, err := WaitForLog(waitCtx, podsClient, "app=test", "server started")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := WaitForLog(ctx, podsClient, "app=test", "(")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+func appendDeadLetter(path string, deadLetter DeadLetter) error {
+	line, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadDeadLetterFile reads dead letters from the file one by one.
+func ReadDeadLetterFile(path string, handle func(deadLetter DeadLetter) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var deadLetter DeadLetter
+			if decodeErr := json.Unmarshal(line, &deadLetter); decodeErr != nil {
+				return fmt.Errorf("decode dead letter at line %d: %w", lineNo, decodeErr)
+			}
+			if handleErr := handle(deadLetter); handleErr != nil {
+				return handleErr
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// ReplayDeadLetterFile replays batches in the dead-letter file to the consumer.
+// The file is left untouched, callers should remove it once replayed.
+func ReplayDeadLetterFile(path string, consumer podstream.LogEntryConsumer) error {
+	return ReadDeadLetterFile(path, func(deadLetter DeadLetter) error {
+		consumer.OnLogs(deadLetter.Logs)
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/b4fun/kubekit"
+)
+
+// WithLogger sets the logger to be used by the sink.
+func WithLogger(logger kubekit.Logger) Option {
+	return func(sink *Sink) error {
+		sink.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client to post batches with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(sink *Sink) error {
+		sink.httpClient = client
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each request posted with the default http client.
+// Defaults to 10 seconds. Clients set by WithHTTPClient use their own timeouts.
+func WithTimeout(timeout time.Duration) Option {
+	return func(sink *Sink) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		sink.timeout = timeout
+		return nil
+	}
+}
+
+// WithHeaders sets the extra headers to send with each request.
+func WithHeaders(headers map[string]string) Option {
+	return func(sink *Sink) error {
+		sink.headers = headers
+		return nil
+	}
+}
+
+// WithBatchSize sets the max entries in one batch.
+func WithBatchSize(size int) Option {
+	return func(sink *Sink) error {
+		if size < 1 {
+			return errors.New("batch size must be positive")
+		}
+
+		sink.maxBatchSize = size
+		return nil
+	}
+}
+
+// WithBatchAge sets the max time an entry can wait in the batch before sending.
+func WithBatchAge(age time.Duration) Option {
+	return func(sink *Sink) error {
+		if age <= 0 {
+			return errors.New("batch age must be positive")
+		}
+
+		sink.maxBatchAge = age
+		return nil
+	}
+}
+
+// WithHMACSecret signs each request with HMAC-SHA256 using the secret.
+// See Sign for the signature scheme.
+func WithHMACSecret(secret []byte) Option {
+	return func(sink *Sink) error {
+		sink.hmacSecret = secret
+		return nil
+	}
+}
+
+// WithRetry sets the max retry attempts and the backoff between attempts.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(sink *Sink) error {
+		if maxRetries < 0 {
+			return errors.New("max retries must not be negative")
+		}
+
+		sink.maxRetries = maxRetries
+		sink.retryBackoff = backoff
+		return nil
+	}
+}
+
+// WithDeadLetterFile sets the file to append undelivered batches to.
+// Batches in the file can be replayed with ReplayDeadLetterFile.
+func WithDeadLetterFile(path string) Option {
+	return func(sink *Sink) error {
+		sink.deadLetterPath = path
+		return nil
+	}
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b4fun/kubekit/internal/logger"
+	"github.com/b4fun/kubekit/podstream"
+)
+
+const (
+	// HeaderSignature is the request header carrying the HMAC signature.
+	HeaderSignature = "X-Podstream-Signature"
+	// HeaderTimestamp is the request header carrying the signing unix timestamp.
+	HeaderTimestamp = "X-Podstream-Timestamp"
+
+	// maxRetryAfter caps the wait time requested by the receiver.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Sign computes the signature for the request body signed at the given unix timestamp.
+// The signature is "sha256=" followed by the hex encoded HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, timestamp)
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sink is a podstream.LogEntryConsumer that posts batches of log entries as JSON to a webhook.
+//
+// Entries are sent once the batch is full or the oldest entry in the batch reaches the max age.
+// Batches that cannot be delivered after retries are appended to the dead-letter file if configured.
+// Callers should Close the sink to flush pending entries.
+type Sink struct {
+	logger logger.Logger
+
+	// url is the webhook url.
+	url string
+
+	// httpClient is the client used to post batches.
+	httpClient *http.Client
+
+	// timeout specifies the timeout of the default http client.
+	timeout time.Duration
+
+	// headers specifies the extra headers to send.
+	headers map[string]string
+
+	// maxBatchSize specifies the max entries in one batch.
+	maxBatchSize int
+
+	// maxBatchAge specifies the max wait time of an entry in the batch.
+	maxBatchAge time.Duration
+
+	// hmacSecret specifies the secret to sign requests with.
+	hmacSecret []byte
+
+	// maxRetries specifies the max retry attempts.
+	maxRetries int
+
+	// retryBackoff specifies the base backoff between retry attempts.
+	retryBackoff time.Duration
+
+	// deadLetterPath specifies the dead-letter file path.
+	deadLetterPath string
+
+	// sleep waits between retry attempts.
+	sleep func(d time.Duration)
+
+	batchLock    sync.Mutex
+	batch        []podstream.LogEntry
+	batchStarted time.Time
+
+	// sendLock is held from taking a batch to delivering it, so the batches are delivered
+	// in the order they are taken.
+	sendLock sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+var _ podstream.LogEntryConsumer = (*Sink)(nil)
+
+// NewSink creates a webhook sink posting to the given url.
+func NewSink(url string, options ...Option) (*Sink, error) {
+	if url == "" {
+		return nil, errors.New("url is required")
+	}
+
+	sink := &Sink{
+		logger:       logger.NoOp,
+		url:          url,
+		timeout:      10 * time.Second,
+		maxBatchSize: 500,
+		maxBatchAge:  5 * time.Second,
+		maxRetries:   3,
+		retryBackoff: 1 * time.Second,
+		sleep:        time.Sleep,
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	for _, opt := range options {
+		if err := opt(sink); err != nil {
+			return nil, err
+		}
+	}
+	if sink.logger == nil {
+		sink.logger = logger.NoOp
+	}
+	if sink.httpClient == nil {
+		sink.httpClient = &http.Client{Timeout: sink.timeout}
+	}
+
+	go sink.flushAged()
+
+	return sink, nil
+}
+
+func (s *Sink) OnLogs(logs []podstream.LogEntry) {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	for _, batch := range s.appendLogs(logs) {
+		s.deliver(batch)
+	}
+}
+
+// appendLogs appends the logs to the pending batch and returns the full batches.
+func (s *Sink) appendLogs(logs []podstream.LogEntry) [][]podstream.LogEntry {
+	s.batchLock.Lock()
+	defer s.batchLock.Unlock()
+
+	var full [][]podstream.LogEntry
+	for _, entry := range logs {
+		if len(s.batch) < 1 {
+			s.batchStarted = time.Now()
+		}
+		s.batch = append(s.batch, entry)
+		if len(s.batch) >= s.maxBatchSize {
+			full = append(full, s.batch)
+			s.batch = nil
+		}
+	}
+
+	return full
+}
+
+// takeBatch takes the pending batch. If force is false, the batch is taken only when it is aged.
+func (s *Sink) takeBatch(force bool) []podstream.LogEntry {
+	s.batchLock.Lock()
+	defer s.batchLock.Unlock()
+
+	if len(s.batch) < 1 {
+		return nil
+	}
+	if !force && time.Since(s.batchStarted) < s.maxBatchAge {
+		return nil
+	}
+
+	batch := s.batch
+	s.batch = nil
+	return batch
+}
+
+func (s *Sink) flushAged() {
+	defer close(s.stopped)
+
+	// check at a finer interval than the max age to bound the overshoot
+	ticker := time.NewTicker(s.maxBatchAge / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sendLock.Lock()
+			if batch := s.takeBatch(false); batch != nil {
+				s.deliver(batch)
+			}
+			s.sendLock.Unlock()
+		}
+	}
+}
+
+// Flush sends the pending batch immediately.
+func (s *Sink) Flush() {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	if batch := s.takeBatch(true); batch != nil {
+		s.deliver(batch)
+	}
+}
+
+// Close flushes the pending batch and stops the sink.
+func (s *Sink) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.stopped
+	s.Flush()
+
+	return nil
+}
+
+// retryableError is a delivery failure that can be retried.
+type retryableError struct {
+	err error
+	// retryAfter is the wait time requested by the receiver.
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// deliver delivers the batch, the caller should hold the sendLock.
+func (s *Sink) deliver(batch []podstream.LogEntry) {
+	body, err := json.Marshal(Payload{Logs: batch})
+	if err != nil {
+		s.deadLetter(batch, fmt.Errorf("encode payload: %w", err))
+		return
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			return
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= s.maxRetries {
+			break
+		}
+
+		wait := s.retryBackoff << attempt
+		if retryable.retryAfter > 0 {
+			wait = retryable.retryAfter
+		}
+		s.logger.Log("retrying webhook delivery in %s: %s", wait, err)
+		s.sleep(wait)
+	}
+
+	s.deadLetter(batch, err)
+}
+
+// parseRetryAfter parses the Retry-After header in either delay seconds or http date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+func (s *Sink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.hmacSecret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign(s.hmacSecret, timestamp, body))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return err
+}
+
+func (s *Sink) deadLetter(batch []podstream.LogEntry, reason error) {
+	s.logger.Log("failed to deliver %d log entries: %s", len(batch), reason)
+	if s.deadLetterPath == "" {
+		return
+	}
+
+	if err := appendDeadLetter(s.deadLetterPath, DeadLetter{
+		Time:   time.Now(),
+		URL:    s.url,
+		Reason: reason.Error(),
+		Logs:   batch,
+	}); err != nil {
+		s.logger.Log("failed to write dead letter: %s", err)
+	}
+}
@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogs(n int) []podstream.LogEntry {
+	var logs []podstream.LogEntry
+	for i := 0; i < n; i++ {
+		logs = append(logs, podstream.LogEntry{Time: time.Now(), Log: "test", Pod: "pod-1"})
+	}
+	return logs
+}
+
+func TestSink_BatchAndSign(t *testing.T) {
+	secret := []byte("secret")
+
+	var (
+		mu       sync.Mutex
+		attempts int
+		batches  [][]podstream.LogEntry
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign(secret, r.Header.Get(HeaderTimestamp), body), r.Header.Get(HeaderSignature))
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		var payload Payload
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		batches = append(batches, payload.Logs)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(
+		server.URL,
+		WithBatchSize(2),
+		WithBatchAge(time.Hour),
+		WithHMACSecret(secret),
+	)
+	assert.NoError(t, err)
+	var waits []time.Duration
+	sink.sleep = func(d time.Duration) {
+		waits = append(waits, d)
+	}
+
+	sink.OnLogs(testLogs(3))
+	assert.Equal(t, []time.Duration{7 * time.Second}, waits, "Retry-After should be honoured")
+
+	mu.Lock()
+	assert.Len(t, batches, 1)
+	mu.Unlock()
+
+	assert.NoError(t, sink.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, batches, 2) {
+		assert.Len(t, batches[0], 2)
+		assert.Len(t, batches[1], 1)
+	}
+}
+
+func TestSink_BatchAge(t *testing.T) {
+	received := make(chan Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received <- payload
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(server.URL, WithBatchAge(20*time.Millisecond))
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	sink.OnLogs(testLogs(1))
+
+	select {
+	case payload := <-received:
+		assert.Len(t, payload.Logs, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("aged batch is not sent")
+	}
+}
+
+func TestSink_Order(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, entry := range payload.Logs {
+			logs = append(logs, entry.Log)
+		}
+	}))
+	defer server.Close()
+
+	// aged batches are flushed while full batches are delivered
+	sink, err := NewSink(server.URL, WithBatchSize(3), WithBatchAge(4*time.Millisecond))
+	assert.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 200; i++ {
+		log := strconv.Itoa(i)
+		expected = append(expected, log)
+		sink.OnLogs([]podstream.LogEntry{{Time: time.Now(), Log: log}})
+	}
+	assert.NoError(t, sink.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, expected, logs, "batches should be delivered in order")
+}
+
+func TestSink_DeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sink, err := NewSink(
+		server.URL,
+		WithBatchSize(2),
+		WithDeadLetterFile(deadLetterPath),
+	)
+	assert.NoError(t, err)
+
+	sink.OnLogs(testLogs(3))
+	assert.NoError(t, sink.Close())
+
+	var deadLetters []DeadLetter
+	assert.NoError(t, ReadDeadLetterFile(deadLetterPath, func(deadLetter DeadLetter) error {
+		deadLetters = append(deadLetters, deadLetter)
+		return nil
+	}))
+	if assert.Len(t, deadLetters, 2) {
+		assert.Equal(t, server.URL, deadLetters[0].URL)
+		assert.Contains(t, deadLetters[0].Reason, "400")
+	}
+
+	var replayed []podstream.LogEntry
+	assert.NoError(t, ReplayDeadLetterFile(deadLetterPath, podstream.LogEntryConsumerFunc(func(logs []podstream.LogEntry) {
+		replayed = append(replayed, logs...)
+	})))
+	assert.Len(t, replayed, 3)
+}
+
+func TestSink_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sink, err := NewSink(
+		server.URL,
+		WithTimeout(20*time.Millisecond),
+		WithRetry(0, time.Millisecond),
+		WithDeadLetterFile(deadLetterPath),
+	)
+	assert.NoError(t, err)
+
+	sink.OnLogs(testLogs(1))
+	assert.NoError(t, sink.Close())
+
+	var deadLetters []DeadLetter
+	assert.NoError(t, ReadDeadLetterFile(deadLetterPath, func(deadLetter DeadLetter) error {
+		deadLetters = append(deadLetters, deadLetter)
+		return nil
+	}))
+	assert.Len(t, deadLetters, 1, "timed out batch should be dead-lettered")
+
+	_, err = NewSink(server.URL, WithTimeout(0))
+	assert.Error(t, err)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid"))
+	assert.Equal(t, maxRetryAfter, parseRetryAfter("86400"))
+
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 30*time.Second && d <= time.Minute, d.String())
+}
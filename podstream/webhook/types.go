@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/b4fun/kubekit/podstream"
+)
+
+// Payload is the JSON body posted to the webhook.
+type Payload struct {
+	// Logs is the batch of log entries.
+	Logs []podstream.LogEntry `json:"logs"`
+}
+
+// DeadLetter is a batch that failed to be delivered.
+// Dead letters are written to the dead-letter file as JSON lines.
+type DeadLetter struct {
+	// Time is the time the batch was given up.
+	Time time.Time `json:"time"`
+	// URL is the webhook url.
+	URL string `json:"url"`
+	// Reason describes the last delivery failure.
+	Reason string `json:"reason"`
+	// Logs is the undelivered batch.
+	Logs []podstream.LogEntry `json:"logs"`
+}
+
+// Option specifies options for configuring the webhook sink.
+type Option func(sink *Sink) error